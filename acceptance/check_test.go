@@ -20,6 +20,15 @@ const noVersionRequest = `
 	}
 }`
 
+const initialHistoryRequest = `
+{
+	"source": {
+		"name": "bosh-aws-xen-hvm-ubuntu-trusty-go_agent",
+		"version_family": "3262.latest",
+		"initial_history": 3
+	}
+}`
+
 const versionFamilyRequest = `
 {
 	"source": {
@@ -107,6 +116,35 @@ var _ = Describe("check", func() {
 		})
 	})
 
+	Context("when no version is specified and an initial_history is set", func() {
+		var command *exec.Cmd
+
+		BeforeEach(func() {
+			command = exec.Command(boshioCheck)
+			command.Stdin = bytes.NewBufferString(initialHistoryRequest)
+		})
+
+		It("returns the most recent versions", func() {
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			<-session.Exited
+			Expect(session.ExitCode()).To(Equal(0))
+
+			result := []stemcellVersion{}
+			err = json.Unmarshal(session.Out.Contents(), &result)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result).To(HaveLen(3))
+			for _, v := range result {
+				Expect(v["version"]).To(HavePrefix("3262."))
+			}
+			Expect(result).NotTo(ContainElement(stemcellVersion{
+				"version": "3262.4",
+			}))
+		})
+	})
+
 	Context("when a version_family is specified", func() {
 		var command *exec.Cmd
 
@@ -1,11 +1,15 @@
 package boshio
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"net/url"
@@ -13,12 +17,22 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
 )
 
+// defaultMaxMetadataSize bounds the stemcell metadata read from bosh.io, the
+// full history of a stemcell line is a few megabytes.
+const defaultMaxMetadataSize = 64 * 1024 * 1024
+
+// maxSignatureSize bounds the detached signatures read into memory.
+const maxSignatureSize = 1024 * 1024
+
 //go:generate counterfeiter -o ../fakes/bar.go --fake-name Bar . bar
 type bar interface {
 	SetTotal(contentLength int64)
@@ -37,31 +51,80 @@ type ranger interface {
 	BuildRange(contentLength int64) ([]string, error)
 }
 
+//go:generate counterfeiter -o ../fakes/limiter.go --fake-name Limiter . limiter
+type limiter interface {
+	Wait(n int)
+}
+
 //go:generate counterfeiter -o ../fakes/http_client.go --fake-name HTTPClient . httpClient
 type httpClient interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// MetadataDocument is the structured form of the stemcell metadata written to
+// metadata.json and metadata.yml.
+type MetadataDocument struct {
+	Name     string `json:"name" yaml:"name"`
+	Version  string `json:"version" yaml:"version"`
+	Flavor   string `json:"flavor" yaml:"flavor"`
+	URL      string `json:"url" yaml:"url"`
+	Size     int64  `json:"size" yaml:"size"`
+	MD5      string `json:"md5" yaml:"md5"`
+	SHA1     string `json:"sha1" yaml:"sha1"`
+	SHA256   string `json:"sha256" yaml:"sha256"`
+	Provider string `json:"provider" yaml:"provider"`
+}
+
 type Client struct {
-	httpClient           httpClient
-	Bar                  bar
-	Ranger               ranger
-	StemcellMetadataPath string
-	ForceRegular         bool
+	httpClient                 httpClient
+	Bar                        bar
+	Ranger                     ranger
+	StemcellMetadataPath       string
+	LatestStemcellMetadataPath string
+	StemcellNamesPath          string
+	ForceRegular               bool
+	MaxMetadataSize            int64
+	Verifier                   *SignatureVerifier
+	// Cache keeps the stemcell metadata between requests, which are
+	// always made in full when it is nil.
+	Cache *MetadataCache
+	// Limiter bounds the aggregate bandwidth of the ranged requests of a
+	// download, which are not throttled when it is nil.
+	Limiter limiter
+	// Transport is used by the minio client for authenticated downloads, the
+	// default minio transport is used when it is nil.
+	Transport http.RoundTripper
 }
 
 func NewClient(httpClient httpClient, b bar, r ranger, forceRegular bool) *Client {
 	return &Client{
-		httpClient:           httpClient,
-		Bar:                  b,
-		Ranger:               r,
-		StemcellMetadataPath: "/api/v1/stemcells/%s?all=1",
-		ForceRegular:         forceRegular,
+		httpClient:                 httpClient,
+		Bar:                        b,
+		Ranger:                     r,
+		StemcellMetadataPath:       "/api/v1/stemcells/%s?all=1",
+		LatestStemcellMetadataPath: "/api/v1/stemcells/%s",
+		StemcellNamesPath:          "/api/v1/stemcells",
+		ForceRegular:               forceRegular,
+		MaxMetadataSize:            defaultMaxMetadataSize,
 	}
 }
 
-func (c *Client) GetStemcells(name string) (Stemcells, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf(c.StemcellMetadataPath, name), nil)
+// GetStemcells fetches every published version of the named stemcell. The
+// entries which cannot be checked or fetched are returned apart, so that a
+// broken entry in the history does not break the other versions.
+func (c *Client) GetStemcells(name string) (Stemcells, InvalidStemcells, error) {
+	return c.getStemcells(fmt.Sprintf(c.StemcellMetadataPath, name))
+}
+
+// GetLatestStemcells fetches only the latest versions of the named stemcell,
+// which is a fraction of the full history.
+func (c *Client) GetLatestStemcells(name string) (Stemcells, InvalidStemcells, error) {
+	return c.getStemcells(fmt.Sprintf(c.LatestStemcellMetadataPath, name))
+}
+
+// GetStemcellNames fetches the names of every stemcell published to bosh.io.
+func (c *Client) GetStemcellNames() ([]string, error) {
+	req, err := http.NewRequest("GET", c.StemcellNamesPath, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -70,20 +133,118 @@ func (c *Client) GetStemcells(name string) (Stemcells, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed fetching metadata - boshio returned: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed fetching stemcell names - boshio returned: %d", resp.StatusCode)
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	var entries []struct {
+		Name string `json:"name"`
+	}
+	err = json.NewDecoder(http.MaxBytesReader(nil, resp.Body, c.MaxMetadataSize)).Decode(&entries)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed decoding stemcell names: %s", err)
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Name == "" || seen[entry.Name] {
+			continue
+		}
+		seen[entry.Name] = true
+		names = append(names, entry.Name)
+	}
+
+	// a response of another shape decodes to no names, which would make
+	// every name look unknown
+	if len(names) == 0 {
+		return nil, errors.New("failed decoding stemcell names: no names were listed")
 	}
 
-	var stemcells []Stemcell
-	err = json.Unmarshal(bodyBytes, &stemcells)
+	return names, nil
+}
+
+// ValidateName returns an UnknownNameError when bosh.io does not publish the
+// name. Failing to list the published names is only reported, so that it does
+// not change what the caller does without the validation.
+func (c *Client) ValidateName(name string) error {
+	names, err := c.GetStemcellNames()
 	if err != nil {
-		return nil, err
+		fmt.Fprintf(os.Stderr, "Unable to validate stemcell name '%s': %s\n", name, err)
+		return nil
+	}
+
+	return CheckName(name, names)
+}
+
+// FindStemcellName returns the name of the stemcell published for the IaaS and
+// operating system.
+func (c *Client) FindStemcellName(iaas string, os string) (string, error) {
+	names, err := c.GetStemcellNames()
+	if err != nil {
+		return "", err
+	}
+
+	return SelectStemcellName(names, iaas, os)
+}
+
+func (c *Client) getStemcells(path string) (Stemcells, InvalidStemcells, error) {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	cacheKey := req.URL.String()
+
+	var cached cacheEntry
+	var isCached bool
+	if c.Cache != nil {
+		cached, isCached = c.Cache.get(cacheKey)
+		if isCached {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var stemcells Stemcells
+	var invalid InvalidStemcells
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		stemcells, invalid, err = c.decodeStemcells(bytes.NewReader(cached.Body))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if resp.StatusCode != http.StatusOK {
+			return nil, nil, fmt.Errorf("failed fetching metadata - boshio returned: %d", resp.StatusCode)
+		}
+
+		body := http.MaxBytesReader(nil, resp.Body, c.MaxMetadataSize)
+
+		// the response is only kept in memory when it is going to be cached
+		var cacheBody bytes.Buffer
+		var r io.Reader = body
+		if c.Cache != nil {
+			r = io.TeeReader(body, &cacheBody)
+		}
+
+		stemcells, invalid, err = c.decodeStemcells(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c.cacheResponse(cacheKey, resp, cacheBody.Bytes())
 	}
 
 	if c.ForceRegular {
@@ -92,82 +253,224 @@ func (c *Client) GetStemcells(name string) (Stemcells, error) {
 		}
 	}
 
-	return stemcells, nil
+	return stemcells, invalid, nil
 }
 
-func (c *Client) WriteMetadata(stemcell Stemcell, metadataKey string, metadataFile io.Writer) error {
-	switch metadataKey {
-	case "url":
-		_, err := metadataFile.Write([]byte(stemcell.Details().URL))
+// decodeStemcells streams the list of stemcells, validating each entry as it
+// is decoded. Entries with fields of the wrong type or missing the fields
+// needed to check and fetch them are skipped and reported, while unknown
+// fields are ignored so that bosh.io can publish new ones.
+func (c *Client) decodeStemcells(r io.Reader) (Stemcells, InvalidStemcells, error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, nil, c.decodeError(err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, nil, errors.New("failed decoding stemcell metadata: expected a list of stemcells")
+	}
+
+	stemcells := Stemcells{}
+	var invalid InvalidStemcells
+	for i := 0; decoder.More(); i++ {
+		var entry json.RawMessage
+		err = decoder.Decode(&entry)
 		if err != nil {
-			return err
+			return nil, nil, c.decodeError(err)
 		}
-	case "sha1":
-		_, err := metadataFile.Write([]byte(stemcell.Details().SHA1))
+
+		stemcell, err := decodeStemcell(entry)
 		if err != nil {
-			return err
+			skipped := InvalidStemcell{Index: i, Version: stemcell.Version, Err: err}
+			fmt.Fprintf(os.Stderr, "Skipping %s\n", skipped)
+			invalid = append(invalid, skipped)
+			continue
 		}
+
+		stemcells = append(stemcells, stemcell)
+	}
+
+	_, err = decoder.Token()
+	if err != nil {
+		return nil, nil, c.decodeError(err)
+	}
+
+	return stemcells, invalid, nil
+}
+
+// decodeStemcell decodes and validates a single entry, returning as much of
+// it as could be decoded along with the error.
+func decodeStemcell(entry json.RawMessage) (Stemcell, error) {
+	var stemcell Stemcell
+	err := json.Unmarshal(entry, &stemcell)
+	if err != nil {
+		// the version still identifies the entry when another field has
+		// the wrong type
+		var identity struct{ Version string }
+		json.Unmarshal(entry, &identity)
+		return Stemcell{Version: identity.Version}, err
+	}
+
+	return stemcell, stemcell.Validate()
+}
+
+func (c *Client) decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("failed decoding stemcell metadata: response exceeds the limit of %d bytes", c.MaxMetadataSize)
+	}
+	return fmt.Errorf("failed decoding stemcell metadata: %s", err)
+}
+
+// cacheResponse stores the response when it has a validator. Failing to cache
+// it only costs a full download on the next request.
+func (c *Client) cacheResponse(cacheKey string, resp *http.Response, body []byte) {
+	if c.Cache == nil {
+		return
+	}
+
+	entry := cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Body:         body,
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return
+	}
+
+	err := c.Cache.put(cacheKey, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to cache the stemcell metadata: %s\n", err)
+	}
+}
+
+func (c *Client) WriteMetadata(stemcell Stemcell, metadataKey string, metadataFile io.Writer) error {
+	var contents []byte
+
+	switch metadataKey {
+	case "url":
+		contents = []byte(stemcell.Details().URL)
+	case "sha1":
+		contents = []byte(stemcell.Details().SHA1)
 	case "sha256":
-		_, err := metadataFile.Write([]byte(stemcell.Details().SHA256))
+		contents = []byte(stemcell.Details().SHA256)
+	case "version":
+		contents = []byte(stemcell.Version)
+	case "metadata.json":
+		var err error
+		contents, err = json.MarshalIndent(c.metadataDocument(stemcell), "", "  ")
 		if err != nil {
 			return err
 		}
-	case "version":
-		_, err := metadataFile.Write([]byte(stemcell.Version))
+	case "metadata.yml":
+		var err error
+		contents, err = yaml.Marshal(c.metadataDocument(stemcell))
 		if err != nil {
 			return err
 		}
+	default:
+		return fmt.Errorf("unknown metadata key '%s'", metadataKey)
+	}
+
+	_, err := metadataFile.Write(contents)
+	return err
+}
+
+func (c *Client) metadataDocument(stemcell Stemcell) MetadataDocument {
+	details := stemcell.Details()
+
+	return MetadataDocument{
+		Name:     stemcell.Name,
+		Version:  stemcell.Version,
+		Flavor:   stemcell.Flavor(),
+		URL:      details.URL,
+		Size:     details.Size,
+		MD5:      details.MD5,
+		SHA1:     details.SHA1,
+		SHA256:   details.SHA256,
+		Provider: details.Provider(),
+	}
+}
+
+// TarballFileName returns the name of the file the stemcell tarball is
+// downloaded to.
+func TarballFileName(stemcell Stemcell, preserveFileName bool) (string, error) {
+	if !preserveFileName {
+		return "stemcell.tgz", nil
+	}
+
+	stemcellUrlObject, err := url.Parse(stemcell.Details().URL)
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	return filepath.Base(stemcellUrlObject.Path), nil
 }
 
-func (c *Client) DownloadStemcell(stemcell Stemcell, location string, preserveFileName bool, auth Auth) error {
+// Transfer describes the download of a tarball: the bytes read from the
+// responses and the time spent transferring them, excluding verification.
+type Transfer struct {
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Throughput is the average number of bytes transferred per second.
+func (t Transfer) Throughput() float64 {
+	if t.Duration <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / t.Duration.Seconds()
+}
+
+func (c *Client) DownloadStemcell(stemcell Stemcell, location string, preserveFileName bool, auth Auth) (Transfer, error) {
 	var contentLength int64
-	var err error
-	stemcellFileName := "stemcell.tgz"
 	stemcellUrl := stemcell.Details().URL
 
-	if preserveFileName {
-		stemcellUrlObject, err := url.Parse(stemcellUrl)
-		if err != nil {
-			return err
-		}
-		stemcellFileName = filepath.Base(stemcellUrlObject.Path)
+	stemcellFileName, err := TarballFileName(stemcell, preserveFileName)
+	if err != nil {
+		return Transfer{}, err
 	}
 
 	if auth.AccessKey != "" {
 		contentLength, err = c.contentLengthWithAuth(stemcellUrl, auth)
 		if err != nil {
-			return fmt.Errorf("failed to fetch object metadata: %s", err)
+			return Transfer{}, fmt.Errorf("failed to fetch object metadata: %s", err)
 		}
 	} else {
 		req, err := http.NewRequest("HEAD", stemcellUrl, nil)
 		if err != nil {
-			return fmt.Errorf("failed to construct HEAD request: %s", err)
+			return Transfer{}, fmt.Errorf("failed to construct HEAD request: %s", err)
 		}
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			return err
+			return Transfer{}, err
+		}
+		if resp.Body != nil {
+			// closing the body allows the connection to be reused
+			resp.Body.Close()
 		}
 		contentLength = resp.ContentLength
 	}
 
 	ranges, err := c.Ranger.BuildRange(contentLength)
 	if err != nil {
-		return err
+		return Transfer{}, err
 	}
 
 	stemcellData, err := os.Create(filepath.Join(location, stemcellFileName))
 	if err != nil {
-		return err
+		return Transfer{}, err
 	}
 	defer stemcellData.Close()
 
 	c.Bar.SetTotal(contentLength)
 	c.Bar.Kickoff()
 
+	var transferred int64
+	start := time.Now()
+
 	var g errgroup.Group
 	for _, r := range ranges {
 		byteRange := r
@@ -195,6 +498,7 @@ func (c *Client) DownloadStemcell(stemcell Stemcell, location string, preserveFi
 				return err
 			}
 
+			atomic.AddInt64(&transferred, int64(len(respBytes)))
 			c.Bar.Add(bytesWritten)
 
 			return nil
@@ -202,34 +506,112 @@ func (c *Client) DownloadStemcell(stemcell Stemcell, location string, preserveFi
 	}
 
 	if err := g.Wait(); err != nil {
-		return err
+		return Transfer{}, err
 	}
+	transfer := Transfer{Bytes: transferred, Duration: time.Since(start)}
 
 	c.Bar.Finish()
 
-	if stemcell.Details().SHA256 == "" {
-		computedSHA := sha1.New()
-		_, err = io.Copy(computedSHA, stemcellData)
+	computed := map[string]hash.Hash{"md5": md5.New(), "sha1": sha1.New(), "sha256": sha256.New()}
+	_, err = io.Copy(io.MultiWriter(computed["md5"], computed["sha1"], computed["sha256"]), stemcellData)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	// every digest published by bosh.io is verified, strongest first
+	details := stemcell.Details()
+	published := []struct {
+		algorithm string
+		expected  string
+	}{{"sha256", details.SHA256}, {"sha1", details.SHA1}, {"md5", details.MD5}}
+
+	verified := map[string]string{}
+	for _, digest := range published {
+		if digest.expected == "" {
+			continue
+		}
+
+		sum := fmt.Sprintf("%x", computed[digest.algorithm].Sum(nil))
+		if sum != digest.expected {
+			return Transfer{}, fmt.Errorf("computed %s %s did not match expected %s of %s", digest.algorithm, sum, digest.algorithm, digest.expected)
+		}
+		verified[digest.algorithm] = sum
+	}
+
+	if len(verified) == 0 {
+		return Transfer{}, fmt.Errorf("stemcell %s version %s publishes no checksum to verify the tarball against", stemcell.Name, stemcell.Version)
+	}
+
+	if c.Verifier != nil {
+		err = c.verifySignature(stemcellUrl, stemcellData, auth)
 		if err != nil {
-			return err
+			return Transfer{}, err
+		}
+	}
+
+	// once the tarball is verified, its verified checksums are written in the
+	// format of sha1sum and sha256sum so that they can be checked with -c
+	for _, extension := range []string{"sha1", "sha256"} {
+		sum, ok := verified[extension]
+		if !ok {
+			continue
 		}
 
-		if fmt.Sprintf("%x", computedSHA.Sum(nil)) != stemcell.Details().SHA1 {
-			return fmt.Errorf("computed sha1 %x did not match expected sha1 of %s", computedSHA.Sum(nil), stemcell.Details().SHA1)
+		checksumLine := fmt.Sprintf("%s  %s\n", sum, stemcellFileName)
+		err = os.WriteFile(filepath.Join(location, stemcellFileName+"."+extension), []byte(checksumLine), 0644)
+		if err != nil {
+			return Transfer{}, err
+		}
+	}
+
+	return transfer, nil
+}
+
+// verifySignature fetches the signature published next to the tarball and
+// verifies the downloaded tarball against it.
+func (c *Client) verifySignature(stemcellURL string, tarball io.ReadSeeker, auth Auth) error {
+	signatureURL := c.Verifier.SignatureURL(stemcellURL)
+
+	var signature []byte
+	if auth.AccessKey != "" {
+		reader, err := c.minioReaderForObject(signatureURL, auth)
+		if err != nil {
+			return fmt.Errorf("failed to download stemcell signature: %s", err)
+		}
+		defer reader.Close()
+
+		signature, err = io.ReadAll(io.LimitReader(reader, maxSignatureSize))
+		if err != nil {
+			return fmt.Errorf("failed to download stemcell signature: %s", err)
 		}
 	} else {
-		computedSHA256 := sha256.New()
-		_, err = io.Copy(computedSHA256, stemcellData)
+		req, err := http.NewRequest("GET", signatureURL, nil)
 		if err != nil {
 			return err
 		}
 
-		if fmt.Sprintf("%x", computedSHA256.Sum(nil)) != stemcell.Details().SHA256 {
-			return fmt.Errorf("computed sha256 %x did not match expected sha256 of %s", computedSHA256.Sum(nil), stemcell.Details().SHA256)
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to download stemcell signature %s - server returned %d", signatureURL, resp.StatusCode)
+		}
+
+		signature, err = io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+		if err != nil {
+			return err
 		}
 	}
 
-	return nil
+	_, err := tarball.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	return c.Verifier.Verify(tarball, signature)
 }
 
 func (c Client) retryableRequest(stemcellURL string, byteRange string) ([]byte, error) {
@@ -253,7 +635,7 @@ func (c Client) retryableRequest(stemcellURL string, byteRange string) ([]byte,
 		}
 
 		var respBytes []byte
-		respBytes, err = io.ReadAll(resp.Body)
+		respBytes, err = io.ReadAll(c.throttle(resp.Body))
 		resp.Body.Close()
 
 		if err != nil {
@@ -268,6 +650,27 @@ func (c Client) retryableRequest(stemcellURL string, byteRange string) ([]byte,
 	}
 }
 
+// throttle limits the reads from r to the bandwidth of the limiter.
+func (c Client) throttle(r io.Reader) io.Reader {
+	if c.Limiter == nil {
+		return r
+	}
+	return throttledReader{reader: r, limiter: c.Limiter}
+}
+
+type throttledReader struct {
+	reader  io.Reader
+	limiter limiter
+}
+
+func (t throttledReader) Read(p []byte) (int, error) {
+	n, err := t.reader.Read(p)
+	if n > 0 {
+		t.limiter.Wait(n)
+	}
+	return n, err
+}
+
 func (c Client) fetchWithAuth(urlString string, bytes int, offset int, auth Auth) ([]byte, error) {
 	reader, err := c.minioReaderForObject(urlString, auth)
 	if err != nil {
@@ -275,7 +678,7 @@ func (c Client) fetchWithAuth(urlString string, bytes int, offset int, auth Auth
 	}
 
 	byteSegment := make([]byte, bytes)
-	_, err = reader.ReadAt(byteSegment, int64(offset))
+	_, err = io.ReadFull(c.throttle(io.NewSectionReader(reader, int64(offset), int64(bytes))), byteSegment)
 	if err != nil {
 		return nil, err
 	}
@@ -301,8 +704,9 @@ func (c Client) minioReaderForObject(urlString string, auth Auth) (*minio.Object
 	bucket, object := pieces[1], pieces[2]
 
 	minioOptions := &minio.Options{
-		Creds:  credentials.NewStaticV4(auth.AccessKey, auth.SecretKey, ""),
-		Secure: parsedUrl.Scheme == "https",
+		Creds:     credentials.NewStaticV4(auth.AccessKey, auth.SecretKey, ""),
+		Secure:    parsedUrl.Scheme == "https",
+		Transport: c.Transport,
 	}
 
 	client, err := minio.New(parsedUrl.Host, minioOptions)
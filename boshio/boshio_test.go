@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -11,7 +12,10 @@ import (
 	"time"
 
 	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/content"
 	"github.com/concourse/bosh-io-stemcell-resource/fakes"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -45,7 +49,7 @@ var _ = Describe("Boshio", func() {
 	Describe("GetStemcells", func() {
 		It("fetches all stemcells for a given name", func() {
 			boshioServer.Start()
-			stemcells, err := client.GetStemcells("some-light-stemcell")
+			stemcells, _, err := client.GetStemcells("some-light-stemcell")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(stemcells).To(Equal(boshio.Stemcells{
@@ -63,6 +67,155 @@ var _ = Describe("Boshio", func() {
 			}))
 		})
 
+		It("requests every version", func() {
+			var requestURIs []string
+			boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+				requestURIs = append(requestURIs, req.URL.RequestURI())
+				lightAPIHandler(w, req)
+			}
+			boshioServer.Start()
+
+			_, _, err := client.GetStemcells("some-light-stemcell")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells/some-light-stemcell?all=1"}))
+		})
+
+		Context("when caching the metadata", func() {
+			var (
+				cacheDir  string
+				requests  []*http.Request
+				responses int
+			)
+
+			BeforeEach(func() {
+				var err error
+				cacheDir, err = os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				client.Cache = boshio.NewMetadataCache(cacheDir)
+
+				requests = nil
+				responses = 0
+				boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+					requests = append(requests, req)
+
+					w.Header().Set("ETag", `"some-etag"`)
+					if req.Header.Get("If-None-Match") == `"some-etag"` {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+
+					responses++
+					lightAPIHandler(w, req)
+				}
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(cacheDir)).To(Succeed())
+			})
+
+			It("reuses the previous response when the metadata has not changed", func() {
+				boshioServer.Start()
+
+				first, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				second, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(second).To(Equal(first))
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[0].Header.Get("If-None-Match")).To(BeEmpty())
+				Expect(requests[1].Header.Get("If-None-Match")).To(Equal(`"some-etag"`))
+				Expect(responses).To(Equal(1))
+			})
+
+			It("shares the cache between clients using the same directory", func() {
+				boshioServer.Start()
+
+				_, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				otherClient := boshio.NewClient(httpClient, bar, ranger, forceRegular)
+				otherClient.Cache = boshio.NewMetadataCache(cacheDir)
+
+				stemcells, _, err := otherClient.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stemcells).To(HaveLen(1))
+				Expect(responses).To(Equal(1))
+			})
+
+			It("sends If-Modified-Since when only Last-Modified is known", func() {
+				boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+					requests = append(requests, req)
+					w.Header().Set("Last-Modified", "Mon, 2 Jan 2006 15:04:05 GMT")
+					lightAPIHandler(w, req)
+				}
+				boshioServer.Start()
+
+				_, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+				_, _, err = client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(requests[1].Header.Get("If-Modified-Since")).To(Equal("Mon, 2 Jan 2006 15:04:05 GMT"))
+				Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+			})
+
+			It("fetches the metadata in full when the cache is unreadable", func() {
+				boshioServer.Start()
+
+				_, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				entries, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(os.WriteFile(entries[0], []byte("corrupt"), 0644)).To(Succeed())
+
+				stemcells, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stemcells).To(HaveLen(1))
+				Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+				Expect(responses).To(Equal(2))
+			})
+		})
+
+		Context("when entries are invalid", func() {
+			BeforeEach(func() {
+				boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte(`[
+						{"name": "a stemcell", "version": "1.2", "light": {"url": "https://example.com/1.2", "sha1": "2222"}, "published_at": "2026-10-19"},
+						{"name": "a stemcell", "version": "1.1", "light": {"url": "https://example.com/1.1", "size": "large", "sha1": "1111"}},
+						{"name": "a stemcell", "version": "1.0", "light": {"sha1": "0000"}}
+					]`))
+				}
+				boshioServer.Start()
+			})
+
+			It("skips them and returns the valid entries", func() {
+				stemcells, _, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stemcells).To(HaveLen(1))
+				Expect(stemcells[0].Version).To(Equal("1.2"))
+			})
+
+			It("reports the index and version of each skipped entry", func() {
+				_, invalid, err := client.GetStemcells("some-light-stemcell")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(invalid).To(HaveLen(2))
+				Expect(invalid[0]).To(MatchError(ContainSubstring("malformed stemcell at index 1 (version '1.1'): json: cannot unmarshal string")))
+				Expect(invalid[1]).To(MatchError("malformed stemcell at index 2 (version '1.0'): light flavor is missing its url"))
+
+				skipped, ok := invalid.FindByVersion("1.0")
+				Expect(ok).To(BeTrue())
+				Expect(skipped.Index).To(Equal(2))
+			})
+		})
+
 		Context("when an error occurs", func() {
 			Context("when bosh.io responds with a non-200", func() {
 				It("returns an error", func() {
@@ -71,14 +224,36 @@ var _ = Describe("Boshio", func() {
 					}
 
 					boshioServer.Start()
-					_, err := client.GetStemcells("some-light-stemcell")
+					_, _, err := client.GetStemcells("some-light-stemcell")
 					Expect(err).To(MatchError("failed fetching metadata - boshio returned: 500"))
 				})
 			})
 
+			Context("when the response is not a list", func() {
+				It("returns an error", func() {
+					boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+						w.Write([]byte(`{"error": "not found"}`))
+					}
+
+					boshioServer.Start()
+					_, _, err := client.GetStemcells("some-light-stemcell")
+					Expect(err).To(MatchError("failed decoding stemcell metadata: expected a list of stemcells"))
+				})
+			})
+
+			Context("when the response exceeds the size limit", func() {
+				It("returns an error", func() {
+					client.MaxMetadataSize = 100
+
+					boshioServer.Start()
+					_, _, err := client.GetStemcells("some-light-stemcell")
+					Expect(err).To(MatchError("failed decoding stemcell metadata: response exceeds the limit of 100 bytes"))
+				})
+			})
+
 			Context("when the get fails", func() {
 				XIt("returns an error", func() {
-					_, err := client.GetStemcells("some-light-stemcell")
+					_, _, err := client.GetStemcells("some-light-stemcell")
 					Expect(err).To(MatchError(ContainSubstring("invalid URL escape")))
 				})
 			})
@@ -90,13 +265,149 @@ var _ = Describe("Boshio", func() {
 					}
 
 					boshioServer.Start()
-					_, err := client.GetStemcells("some-light-stemcell")
+					_, _, err := client.GetStemcells("some-light-stemcell")
 					Expect(err).To(MatchError(ContainSubstring("invalid character")))
 				})
 			})
 		})
 	})
 
+	Describe("GetLatestStemcells", func() {
+		It("requests only the latest versions", func() {
+			var requestURIs []string
+			boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+				requestURIs = append(requestURIs, req.URL.RequestURI())
+				lightAPIHandler(w, req)
+			}
+			boshioServer.Start()
+
+			stemcells, _, err := client.GetLatestStemcells("some-light-stemcell")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stemcells).To(HaveLen(1))
+			Expect(stemcells[0].Version).To(Equal("some version"))
+
+			Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells/some-light-stemcell"}))
+		})
+
+		It("caches the latest versions separately from every version", func() {
+			var requestURIs []string
+			boshioServer.LightAPIHandler = func(w http.ResponseWriter, req *http.Request) {
+				requestURIs = append(requestURIs, req.URL.RequestURI())
+				w.Header().Set("ETag", `"`+req.URL.RequestURI()+`"`)
+				if req.Header.Get("If-None-Match") == `"`+req.URL.RequestURI()+`"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				lightAPIHandler(w, req)
+			}
+			boshioServer.Start()
+			cacheDir, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(cacheDir)
+			client.Cache = boshio.NewMetadataCache(cacheDir)
+
+			_, _, err = client.GetLatestStemcells("some-light-stemcell")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = client.GetStemcells("some-light-stemcell")
+			Expect(err).NotTo(HaveOccurred())
+			stemcells, _, err := client.GetLatestStemcells("some-light-stemcell")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stemcells).To(HaveLen(1))
+
+			Expect(requestURIs).To(Equal([]string{
+				"/api/v1/stemcells/some-light-stemcell",
+				"/api/v1/stemcells/some-light-stemcell?all=1",
+				"/api/v1/stemcells/some-light-stemcell",
+			}))
+		})
+	})
+
+	Describe("GetStemcellNames", func() {
+		It("fetches the distinct names of every stemcell", func() {
+			var requestURIs []string
+			boshioServer.mux.HandleFunc("/api/v1/stemcells", func(w http.ResponseWriter, req *http.Request) {
+				requestURIs = append(requestURIs, req.URL.RequestURI())
+				w.Write([]byte(`[
+					{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", "version": "1.1"},
+					{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", "version": "1.0"},
+					{"name": "bosh-google-kvm-ubuntu-jammy-go_agent", "version": "1.1"}
+				]`))
+			})
+			boshioServer.Start()
+
+			names, err := client.GetStemcellNames()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(Equal([]string{
+				"bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+				"bosh-google-kvm-ubuntu-jammy-go_agent",
+			}))
+
+			Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells"}))
+		})
+
+		It("returns an error when the names cannot be listed", func() {
+			boshioServer.Start()
+
+			_, err := client.GetStemcellNames()
+			Expect(err).To(MatchError("failed fetching stemcell names - boshio returned: 404"))
+		})
+	})
+
+	Describe("ValidateName", func() {
+		Context("when the names can be listed", func() {
+			BeforeEach(func() {
+				boshioServer.mux.HandleFunc("/api/v1/stemcells", func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte(`[{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent"}]`))
+				})
+				boshioServer.Start()
+			})
+
+			It("accepts a published name", func() {
+				Expect(client.ValidateName("bosh-aws-xen-hvm-ubuntu-jammy-go_agent")).To(Succeed())
+			})
+
+			It("suggests the closest published names for an unknown name", func() {
+				err := client.ValidateName("bosh-aws-xen-hvm-ubuntu-jamy-go_agent")
+				Expect(err).To(MatchError("stemcell 'bosh-aws-xen-hvm-ubuntu-jamy-go_agent' is not published on bosh.io, did you mean 'bosh-aws-xen-hvm-ubuntu-jammy-go_agent'?"))
+			})
+		})
+
+		It("leaves the name alone when the names endpoint is not found", func() {
+			boshioServer.Start()
+
+			Expect(client.ValidateName("bosh-aws-xen-hvm-ubuntu-jamy-go_agent")).To(Succeed())
+		})
+
+		DescribeTable("leaves the name alone when the names endpoint returns unexpected json",
+			func(body string) {
+				boshioServer.mux.HandleFunc("/api/v1/stemcells", func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte(body))
+				})
+				boshioServer.Start()
+
+				Expect(client.ValidateName("bosh-aws-xen-hvm-ubuntu-jamy-go_agent")).To(Succeed())
+			},
+			Entry("an object", `{"error": "not found"}`),
+			Entry("entries without names", `[{"id": 1}, {"id": 2}]`),
+		)
+	})
+
+	Describe("FindStemcellName", func() {
+		It("finds the name of the stemcell for the iaas and os", func() {
+			boshioServer.mux.HandleFunc("/api/v1/stemcells", func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte(`[
+					{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent"},
+					{"name": "bosh-google-kvm-ubuntu-jammy-go_agent"}
+				]`))
+			})
+			boshioServer.Start()
+
+			name, err := client.FindStemcellName("google", "ubuntu-jammy")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("bosh-google-kvm-ubuntu-jammy-go_agent"))
+		})
+	})
+
 	Describe("WriteMetadata", func() {
 		var fileLocation *os.File
 
@@ -147,6 +458,67 @@ var _ = Describe("Boshio", func() {
 			Expect(string(version)).To(Equal("some version"))
 		})
 
+		It("writes the metadata.json to disk", func() {
+			stemcell := boshio.Stemcell{
+				Name:    "some-stemcell",
+				Version: "some version",
+				Light: &boshio.Metadata{
+					URL:    "https://example.com/light-stemcell.tgz",
+					Size:   100,
+					MD5:    "qqqq",
+					SHA1:   "2222",
+					SHA256: "4444",
+				},
+			}
+
+			err := client.WriteMetadata(stemcell, "metadata.json", fileLocation)
+			Expect(err).NotTo(HaveOccurred())
+
+			metadata, err := os.ReadFile(fileLocation.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(MatchJSON(`{
+				"name": "some-stemcell",
+				"version": "some version",
+				"flavor": "light",
+				"url": "https://example.com/light-stemcell.tgz",
+				"size": 100,
+				"md5": "qqqq",
+				"sha1": "2222",
+				"sha256": "4444",
+				"provider": "example.com"
+			}`))
+		})
+
+		It("writes the metadata.yml to disk", func() {
+			stemcell := boshio.Stemcell{
+				Name:    "some-stemcell",
+				Version: "some version",
+				Regular: &boshio.Metadata{
+					URL:  "https://example.com/stemcell.tgz",
+					Size: 2000,
+					MD5:  "zzzz",
+					SHA1: "asdf",
+				},
+			}
+
+			err := client.WriteMetadata(stemcell, "metadata.yml", fileLocation)
+			Expect(err).NotTo(HaveOccurred())
+
+			metadata, err := os.ReadFile(fileLocation.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(MatchYAML(`
+name: some-stemcell
+version: some version
+flavor: regular
+url: https://example.com/stemcell.tgz
+size: 2000
+md5: zzzz
+sha1: asdf
+sha256: ""
+provider: example.com
+`))
+		})
+
 		Context("when an error occurs", func() {
 			Context("when url writer fails", func() {
 				It("returns an error", func() {
@@ -162,6 +534,13 @@ var _ = Describe("Boshio", func() {
 				})
 			})
 
+			Context("when the metadata key is unknown", func() {
+				It("returns an error", func() {
+					err := client.WriteMetadata(boshio.Stemcell{Regular: &boshio.Metadata{}}, "md5", fileLocation)
+					Expect(err).To(MatchError("unknown metadata key 'md5'"))
+				})
+			})
+
 			Context("when version writer fails", func() {
 				It("returns an error", func() {
 					err := client.WriteMetadata(boshio.Stemcell{Name: "some-heavy-stemcell", Regular: &boshio.Metadata{}}, "version", fakes.NoopWriter{})
@@ -187,7 +566,7 @@ var _ = Describe("Boshio", func() {
 				Regular: &boshio.Metadata{
 					URL:  serverPath("path/to/light-different-stemcell.tgz"),
 					Size: 100,
-					MD5:  "qqqq",
+					MD5:  "83f21f69bce60330b2f1c18e9c5d3736",
 					SHA1: "5f8d38fd6bb6fd12fcaa284c7132b64cbb20ea4e",
 				},
 			}
@@ -198,7 +577,7 @@ var _ = Describe("Boshio", func() {
 			location, err := os.MkdirTemp("", "")
 			Expect(err).NotTo(HaveOccurred())
 
-			err = client.DownloadStemcell(stubStemcell, location, false, auth)
+			_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
 			Expect(err).NotTo(HaveOccurred())
 
 			content, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
@@ -207,12 +586,72 @@ var _ = Describe("Boshio", func() {
 			Expect(string(content)).To(Equal("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of.."))
 		})
 
+		It("reports the bytes read from the responses rather than the published size", func() {
+			stubStemcell.Regular.Size = 4096
+			boshioServer.Start()
+			location, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			transfer, err := client.DownloadStemcell(stubStemcell, location, false, auth)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(transfer.Bytes).To(Equal(int64(100)))
+			Expect(transfer.Duration).To(BeNumerically(">", 0))
+			Expect(transfer.Throughput()).To(BeNumerically(">", 0))
+		})
+
+		It("writes sha1sum and sha256sum compatible checksum files", func() {
+			stubStemcell.Regular.SHA256 = "df70d54d81094646c767702cbf574055f6d90badc2d16c9c5c5f4e167ea208eb"
+			boshioServer.Start()
+			location, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+			Expect(err).NotTo(HaveOccurred())
+
+			sha1Checksum, err := os.ReadFile(filepath.Join(location, "stemcell.tgz.sha1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(sha1Checksum)).To(Equal("5f8d38fd6bb6fd12fcaa284c7132b64cbb20ea4e  stemcell.tgz\n"))
+
+			sha256Checksum, err := os.ReadFile(filepath.Join(location, "stemcell.tgz.sha256"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(sha256Checksum)).To(Equal("df70d54d81094646c767702cbf574055f6d90badc2d16c9c5c5f4e167ea208eb  stemcell.tgz\n"))
+		})
+
+		It("only writes checksum files for the published digests", func() {
+			boshioServer.Start()
+			location, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(location, "stemcell.tgz.sha1")).To(BeARegularFile())
+			Expect(filepath.Join(location, "stemcell.tgz.sha256")).NotTo(BeAnExistingFile())
+		})
+
+		It("names the checksum files after the preserved filename", func() {
+			boshioServer.Start()
+			location, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
+			Expect(err).NotTo(HaveOccurred())
+
+			sha1Checksum, err := os.ReadFile(filepath.Join(location, "light-different-stemcell.tgz.sha1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(sha1Checksum)).To(Equal("5f8d38fd6bb6fd12fcaa284c7132b64cbb20ea4e  light-different-stemcell.tgz\n"))
+
+			_, err = os.Stat(filepath.Join(location, "light-different-stemcell.tgz.sha256"))
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("uses the stemcell filename from bosh.io when the preserveFileName param is set to true", func() {
 			boshioServer.Start()
 			location, err := os.MkdirTemp("", "")
 			Expect(err).NotTo(HaveOccurred())
 
-			err = client.DownloadStemcell(stubStemcell, location, true, auth)
+			_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
 			Expect(err).NotTo(HaveOccurred())
 
 			content, err := os.ReadFile(filepath.Join(location, "light-different-stemcell.tgz"))
@@ -235,7 +674,34 @@ var _ = Describe("Boshio", func() {
 				location, err := os.MkdirTemp("", "")
 				Expect(err).NotTo(HaveOccurred())
 
-				err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(content)).To(Equal("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of.."))
+			})
+
+			It("uses the configured transport for the bucket", func() {
+				s3Backend := s3mem.New()
+				Expect(s3Backend.CreateBucket("bucket_name")).To(Succeed())
+				_, err := s3Backend.PutObject("bucket_name", "path/to/heavy-stemcell.tgz", map[string]string{"Last-Modified": "Mon, 2 Jan 2006 15:04:05 GMT"}, strings.NewReader("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of.."), 100)
+				Expect(err).NotTo(HaveOccurred())
+
+				bucket := httptest.NewTLSServer(gofakes3.New(s3Backend).Server())
+				defer bucket.Close()
+
+				transport, err := boshio.TransportConfig{CACert: certificatePEM(bucket.Certificate())}.Transport()
+				Expect(err).NotTo(HaveOccurred())
+				client.Transport = transport
+
+				stubStemcell.Regular.URL = bucket.URL + "/bucket_name/path/to/heavy-stemcell.tgz"
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
 				Expect(err).NotTo(HaveOccurred())
 
 				content, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
@@ -251,11 +717,246 @@ var _ = Describe("Boshio", func() {
 				})
 
 				It("returns an error", func() {
-					err := client.DownloadStemcell(stubStemcell, "", false, auth)
+					_, err := client.DownloadStemcell(stubStemcell, "", false, auth)
 					Expect(err).To(MatchError(ContainSubstring("failed to fetch object metadata:")))
 				})
 			})
 		})
+
+		Context("when the bandwidth is limited", func() {
+			It("throttles every byte of the ranged requests", func() {
+				limiter := &fakes.Limiter{}
+				client.Limiter = limiter
+
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				throttled := 0
+				for i := 0; i < limiter.WaitCallCount(); i++ {
+					throttled += limiter.WaitArgsForCall(i)
+				}
+				Expect(throttled).To(Equal(100))
+			})
+
+			It("throttles every byte of authenticated downloads", func() {
+				limiter := &fakes.Limiter{}
+				client.Limiter = limiter
+
+				auth = boshio.Auth{AccessKey: "access key", SecretKey: "secret key"}
+				stubStemcell.Regular.URL = serverPath("bucket_name/path/to/heavy-stemcell.tgz")
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				throttled := 0
+				for i := 0; i < limiter.WaitCallCount(); i++ {
+					throttled += limiter.WaitArgsForCall(i)
+				}
+				Expect(throttled).To(Equal(100))
+			})
+
+			It("bounds the aggregate throughput of the concurrent ranges", func() {
+				start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+				clock := &fakes.Clock{}
+				clock.NowReturns(start)
+				client.Limiter = content.NewLimiterWithClock(10, clock)
+
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				var deadline time.Time
+				for i := 0; i < clock.SleepUntilCallCount(); i++ {
+					if clock.SleepUntilArgsForCall(i).After(deadline) {
+						deadline = clock.SleepUntilArgsForCall(i)
+					}
+				}
+
+				// 100 bytes at 10 bytes per second
+				Expect(deadline.Sub(start)).To(Equal(10 * time.Second))
+			})
+		})
+
+		Context("when a proxy is configured", func() {
+			var p *proxy
+
+			BeforeEach(func() {
+				boshioServer.Start()
+				p = newProxy(boshioServer.URL())
+
+				var err error
+				httpClient, err = boshio.NewHTTPClientWithConfig("http://bosh.test", 800*time.Millisecond, boshio.TransportConfig{Proxy: p.URL()})
+				Expect(err).NotTo(HaveOccurred())
+
+				client = boshio.NewClient(httpClient, bar, ranger, forceRegular)
+				client.Transport = httpClient.Client.Transport
+
+				ranger.BuildRangeReturns([]string{"0-49", "50-99"}, nil)
+			})
+
+			AfterEach(func() {
+				p.Close()
+			})
+
+			It("sends the HEAD and ranged requests through the proxy", func() {
+				stubStemcell.Regular.URL = "http://stemcells.test/path/to/light-different-stemcell.tgz"
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(p.Requests()).To(ConsistOf(
+					"HEAD http://stemcells.test/path/to/light-different-stemcell.tgz",
+					"GET http://stemcells.test/path/to/light-different-stemcell.tgz",
+					"GET http://stemcells.test/path/to/light-different-stemcell.tgz",
+				))
+			})
+
+			It("sends the requests of authenticated downloads through the proxy", func() {
+				auth = boshio.Auth{AccessKey: "access key", SecretKey: "secret key"}
+				stubStemcell.Regular.URL = "http://bucket.test/bucket_name/path/to/heavy-stemcell.tgz"
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of.."))
+
+				// the bucket location is looked up by each concurrent request
+				// until it is cached, so only the object requests are counted
+				var objectRequests []string
+				for _, request := range p.Requests() {
+					if request != "GET http://bucket.test/bucket_name/?location=" {
+						objectRequests = append(objectRequests, request)
+					}
+				}
+				Expect(objectRequests).To(ConsistOf(
+					"HEAD http://bucket.test/bucket_name/path/to/heavy-stemcell.tgz",
+					"GET http://bucket.test/bucket_name/path/to/heavy-stemcell.tgz",
+					"GET http://bucket.test/bucket_name/path/to/heavy-stemcell.tgz",
+				))
+			})
+		})
+
+		Context("when a socks5 proxy is configured", func() {
+			var p *socksProxy
+
+			BeforeEach(func() {
+				boshioServer.Start()
+				p = newSocksProxy(boshioServer.s.Listener.Addr().String())
+
+				var err error
+				httpClient, err = boshio.NewHTTPClientWithConfig("http://bosh.test", 800*time.Millisecond, boshio.TransportConfig{Proxy: p.URL()})
+				Expect(err).NotTo(HaveOccurred())
+
+				client = boshio.NewClient(httpClient, bar, ranger, forceRegular)
+				client.Transport = httpClient.Client.Transport
+
+				ranger.BuildRangeReturns([]string{"0-49", "50-99"}, nil)
+			})
+
+			AfterEach(func() {
+				p.Close()
+			})
+
+			It("connects to the stemcell host through the proxy", func() {
+				stubStemcell.Regular.URL = "http://stemcells.test/path/to/light-different-stemcell.tgz"
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(p.Addresses()).NotTo(BeEmpty())
+				for _, address := range p.Addresses() {
+					Expect(address).To(Equal("stemcells.test:80"))
+				}
+			})
+
+			It("connects to the bucket of authenticated downloads through the proxy", func() {
+				auth = boshio.Auth{AccessKey: "access key", SecretKey: "secret key"}
+				stubStemcell.Regular.URL = "http://bucket.test/bucket_name/path/to/heavy-stemcell.tgz"
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of.."))
+
+				Expect(p.Addresses()).NotTo(BeEmpty())
+				for _, address := range p.Addresses() {
+					Expect(address).To(Equal("bucket.test:80"))
+				}
+			})
+		})
+
+		Context("when verifying signatures", func() {
+			var sign func(string) []byte
+
+			BeforeEach(func() {
+				var publicKey string
+				publicKey, sign = cosignKey()
+
+				var err error
+				client.Verifier, err = boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "cosign", PublicKey: publicKey})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("verifies the tarball against the signature next to it", func() {
+				boshioServer.SignatureHandler = func(w http.ResponseWriter, req *http.Request) {
+					w.Write(sign("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of.."))
+				}
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(location, "stemcell.tgz.sha1")).To(BeAnExistingFile())
+			})
+
+			It("returns an error when the signature does not match", func() {
+				boshioServer.SignatureHandler = func(w http.ResponseWriter, req *http.Request) {
+					w.Write(sign("some other tarball"))
+				}
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).To(MatchError("failed to verify stemcell signature: invalid ecdsa signature"))
+
+				Expect(filepath.Join(location, "stemcell.tgz.sha256")).NotTo(BeAnExistingFile())
+			})
+
+			It("returns an error when there is no signature", func() {
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				Expect(err).To(MatchError(ContainSubstring("failed to download stemcell signature")))
+				Expect(err).To(MatchError(ContainSubstring("server returned 404")))
+			})
+		})
 	})
 
 	Context("when an error occurs", func() {
@@ -267,7 +968,7 @@ var _ = Describe("Boshio", func() {
 				Regular: &boshio.Metadata{
 					URL:  serverPath("path/to/light-different-stemcell.tgz"),
 					Size: 100,
-					MD5:  "qqqq",
+					MD5:  "83f21f69bce60330b2f1c18e9c5d3736",
 					SHA1: "2222",
 				},
 			}
@@ -307,12 +1008,12 @@ var _ = Describe("Boshio", func() {
 					Regular: &boshio.Metadata{
 						URL:  serverPath("path/to/light-different-stemcell.tgz"),
 						Size: 100,
-						MD5:  "qqqq",
+						MD5:  "d672e9bcefbf6d3f21cf3553fc5dbf6e",
 						SHA1: "1c36c7afa4e21e2ccc0c386f790560672534723a",
 					},
 				}
 
-				err = client.DownloadStemcell(stubStemcell, location, false, auth)
+				_, err = client.DownloadStemcell(stubStemcell, location, false, auth)
 				Expect(err).NotTo(HaveOccurred())
 
 				content, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
@@ -330,12 +1031,12 @@ var _ = Describe("Boshio", func() {
 					Regular: &boshio.Metadata{
 						URL:  "%%%%",
 						Size: 100,
-						MD5:  "qqqq",
+						MD5:  "83f21f69bce60330b2f1c18e9c5d3736",
 						SHA1: "1c36c7afa4e21e2ccc0c386f790560672534723a",
 					},
 				}
 
-				err := client.DownloadStemcell(stubStemcell, "", false, auth)
+				_, err := client.DownloadStemcell(stubStemcell, "", false, auth)
 				Expect(err).To(MatchError(ContainSubstring("failed to construct HEAD request:")))
 			})
 		})
@@ -345,7 +1046,7 @@ var _ = Describe("Boshio", func() {
 				ranger.BuildRangeReturns([]string{}, errors.New("failed to build a range"))
 				boshioServer.Start()
 
-				err := client.DownloadStemcell(stubStemcell, "", true, auth)
+				_, err := client.DownloadStemcell(stubStemcell, "", true, auth)
 				Expect(err).To(MatchError("failed to build a range"))
 			})
 		})
@@ -361,7 +1062,7 @@ var _ = Describe("Boshio", func() {
 				err = location.Close()
 				Expect(err).NotTo(HaveOccurred())
 
-				err = client.DownloadStemcell(stubStemcell, location.Name(), true, auth)
+				_, err = client.DownloadStemcell(stubStemcell, location.Name(), true, auth)
 				Expect(err).To(MatchError(ContainSubstring("not a directory")))
 			})
 		})
@@ -372,7 +1073,7 @@ var _ = Describe("Boshio", func() {
 				location, err := os.MkdirTemp("", "")
 				Expect(err).NotTo(HaveOccurred())
 
-				err = client.DownloadStemcell(stubStemcell, location, true, auth)
+				_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
 				Expect(err).To(MatchError("computed sha1 da39a3ee5e6b4b0d3255bfef95601890afd80709 did not match expected sha1 of 2222"))
 			})
 		})
@@ -384,11 +1085,50 @@ var _ = Describe("Boshio", func() {
 				location, err := os.MkdirTemp("", "")
 				Expect(err).NotTo(HaveOccurred())
 
-				err = client.DownloadStemcell(stubStemcell, location, true, auth)
+				_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
 				Expect(err).To(MatchError("computed sha256 e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 did not match expected sha256 of 4444"))
 			})
 		})
 
+		Context("when the sha1 does not match a matching sha256", func() {
+			It("returns an error", func() {
+				stubStemcell.Regular.SHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
+				Expect(err).To(MatchError("computed sha1 da39a3ee5e6b4b0d3255bfef95601890afd80709 did not match expected sha1 of 2222"))
+			})
+		})
+
+		Context("when the md5 cannot be verified", func() {
+			It("returns an error", func() {
+				stubStemcell.Regular.SHA1 = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+				stubStemcell.Regular.MD5 = "qqqq"
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
+				Expect(err).To(MatchError("computed md5 d41d8cd98f00b204e9800998ecf8427e did not match expected md5 of qqqq"))
+				Expect(filepath.Join(location, "light-different-stemcell.tgz.sha1")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("when no checksum is published", func() {
+			It("returns an error", func() {
+				stubStemcell.Regular.SHA1 = ""
+				stubStemcell.Regular.MD5 = ""
+				boshioServer.Start()
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
+				Expect(err).To(MatchError("stemcell different-stemcell version 2222 publishes no checksum to verify the tarball against"))
+			})
+		})
+
 		Context("when the get request is not successful", func() {
 			It("returns an error", func() {
 				ranger.BuildRangeReturns([]string{"0-9"}, nil)
@@ -400,7 +1140,7 @@ var _ = Describe("Boshio", func() {
 				location, err := os.MkdirTemp("", "")
 				Expect(err).NotTo(HaveOccurred())
 
-				err = client.DownloadStemcell(stubStemcell, location, true, auth)
+				_, err = client.DownloadStemcell(stubStemcell, location, true, auth)
 				Expect(err).To(MatchError(ContainSubstring("failed to download stemcell - boshio returned 500")))
 			})
 		})
@@ -0,0 +1,76 @@
+package boshio
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MetadataCache persists the responses of the bosh.io API with their
+// validators, so that unchanged metadata is not downloaded again.
+type MetadataCache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+	Body         []byte `json:"body"`
+}
+
+func NewMetadataCache(dir string) *MetadataCache {
+	return &MetadataCache{dir: dir}
+}
+
+// get returns the cached response for the url, if any. An unreadable entry is
+// treated as missing.
+func (m *MetadataCache) get(url string) (cacheEntry, bool) {
+	contents, err := os.ReadFile(m.path(url))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	err = json.Unmarshal(contents, &entry)
+	if err != nil || (entry.ETag == "" && entry.LastModified == "") {
+		return cacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (m *MetadataCache) put(url string, entry cacheEntry) error {
+	err := os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// entries are replaced atomically, as concurrent checks may share the
+	// cache directory
+	tmp, err := os.CreateTemp(m.dir, ".entry-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), m.path(url))
+}
+
+func (m *MetadataCache) path(url string) string {
+	return filepath.Join(m.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(url))))
+}
@@ -1,34 +1,163 @@
 package boshio
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
+
+	"golang.org/x/net/http/httpproxy"
 )
 
+// TransportConfig configures the connections to bosh.io and the buckets the
+// stemcells are downloaded from.
+type TransportConfig struct {
+	CACert             string   `json:"ca_cert"`
+	ClientCert         string   `json:"client_cert"`
+	ClientKey          string   `json:"client_key"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"`
+	Proxy              string   `json:"proxy"`
+	NoProxy            []string `json:"no_proxy"`
+	DisableKeepAlives  bool     `json:"disable_keep_alives"`
+	HTTP2              bool     `json:"http2"`
+
+	// Concurrency is the number of requests made in parallel, which bounds
+	// the pool of idle connections kept for reuse.
+	Concurrency int `json:"-"`
+}
+
+const defaultConcurrency = 10
+
 func NewHTTPClient(host string, wait time.Duration) HTTPClient {
+	// the zero config uses the system roots only and cannot fail
+	client, _ := NewHTTPClientWithConfig(host, wait, TransportConfig{})
+	return client
+}
+
+func NewHTTPClientWithConfig(host string, wait time.Duration, config TransportConfig) (HTTPClient, error) {
+	transport, err := config.Transport()
+	if err != nil {
+		return HTTPClient{}, err
+	}
+
 	return HTTPClient{
-		Host: host,
-		Wait: wait,
-		Client: &http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-
-				Dial: (&net.Dialer{
-					Timeout: 30 * time.Second,
-					// The OS determines the number of failed keepalive probes before the connection is closed.
-					// The default is 9 retries on Linux.
-					KeepAlive: 30 * time.Second,
-				}).Dial,
-
-				TLSHandshakeTimeout: 60 * time.Second,
-				DisableKeepAlives:   true, // don't re-use TCP connections between requests
-			},
-		},
+		Host:   host,
+		Wait:   wait,
+		Client: &http.Client{Transport: transport},
+	}, nil
+}
+
+// Transport builds the transport used for every request, including the ones
+// made by the minio client for authenticated downloads.
+func (c TransportConfig) Transport() (*http.Transport, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := c.ProxyFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
 	}
+
+	return &http.Transport{
+		Proxy: proxy,
+
+		DialContext: (&net.Dialer{
+			Timeout: 30 * time.Second,
+			// The OS determines the number of failed keepalive probes before the connection is closed.
+			// The default is 9 retries on Linux.
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 60 * time.Second,
+
+		// connections are reused across the ranged requests of a download,
+		// keeping at most one idle connection per concurrent request
+		DisableKeepAlives:   c.DisableKeepAlives,
+		MaxIdleConns:        concurrency,
+		MaxIdleConnsPerHost: concurrency,
+		IdleConnTimeout:     90 * time.Second,
+
+		// a custom TLS config disables HTTP/2 unless it is forced
+		ForceAttemptHTTP2: c.HTTP2,
+	}, nil
+}
+
+// ProxyFunc sends requests through the configured proxy, or the one in the
+// environment, unless their host matches no_proxy.
+func (c TransportConfig) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxyConfig := httpproxy.FromEnvironment()
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy: %s", err)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme '%s': must be one of http, https or socks5", proxyURL.Scheme)
+		}
+
+		proxyConfig.HTTPProxy = c.Proxy
+		proxyConfig.HTTPSProxy = c.Proxy
+	}
+
+	if len(c.NoProxy) > 0 {
+		proxyConfig.NoProxy = strings.Join(c.NoProxy, ",")
+	}
+
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// TLSConfig trusts the CA certificate in addition to the system roots and
+// presents the client certificate when one is configured.
+func (c TransportConfig) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+			return nil, errors.New("failed to parse ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.ClientCert != "" || c.ClientKey != "" {
+		if c.ClientCert == "" || c.ClientKey == "" {
+			return nil, errors.New("client_cert and client_key must be provided together")
+		}
+
+		certificate, err := tls.X509KeyPair([]byte(c.ClientCert), []byte(c.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client_cert and client_key: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
 }
 
 type HTTPClient struct {
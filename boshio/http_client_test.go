@@ -1,14 +1,28 @@
 package boshio_test
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/content"
+	"github.com/concourse/bosh-io-stemcell-resource/fakes"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -39,6 +53,48 @@ func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return &http.Response{StatusCode: http.StatusOK}, nil
 }
 
+// certificatePEM encodes the certificate of a TLS test server.
+func certificatePEM(cert *x509.Certificate) string {
+	return certificatePEMBytes(cert.Raw)
+}
+
+func certificatePEMBytes(der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func sha1Hex(contents []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(contents))
+}
+
+// generateClientCertificate returns a self-signed client certificate and its
+// key as PEM.
+func generateClientCertificate() (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "stemcell-resource"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return certificatePEM(cert), string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})), cert
+}
+
 var _ = Describe("HTTPClient", func() {
 	const waitTime = 10 * time.Millisecond
 	Describe("Do", func() {
@@ -112,7 +168,219 @@ var _ = Describe("HTTPClient", func() {
 			})
 		})
 
+		Context("when the server uses a private CA", func() {
+			var server *httptest.Server
+
+			BeforeEach(func() {
+				server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			get := func(config boshio.TransportConfig) error {
+				client, err := boshio.NewHTTPClientWithConfig(server.URL, waitTime, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				request, err := http.NewRequest("GET", "/", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.Do(request)
+				return err
+			}
+
+			It("fails with the system roots only", func() {
+				Expect(get(boshio.TransportConfig{})).To(MatchError(ContainSubstring("certificate")))
+			})
+
+			It("trusts the configured ca_cert", func() {
+				Expect(get(boshio.TransportConfig{CACert: certificatePEM(server.Certificate())})).To(Succeed())
+			})
+
+			It("skips verification when insecure_skip_verify is set", func() {
+				Expect(get(boshio.TransportConfig{InsecureSkipVerify: true})).To(Succeed())
+			})
+		})
+
+		Context("when the server requires a client certificate", func() {
+			It("presents the configured client_cert", func() {
+				clientCert, clientKey, cert := generateClientCertificate()
+
+				clientCAs := x509.NewCertPool()
+				clientCAs.AddCert(cert)
+
+				server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					Expect(req.TLS.PeerCertificates).To(HaveLen(1))
+					Expect(req.TLS.PeerCertificates[0].Subject.CommonName).To(Equal("stemcell-resource"))
+				}))
+				server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+				server.StartTLS()
+				defer server.Close()
+
+				client, err := boshio.NewHTTPClientWithConfig(server.URL, waitTime, boshio.TransportConfig{
+					CACert:     certificatePEM(server.Certificate()),
+					ClientCert: clientCert,
+					ClientKey:  clientKey,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				request, err := http.NewRequest("GET", "/", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				response, err := client.Do(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when downloading a stemcell in ranges", func() {
+			var tarball []byte
+
+			BeforeEach(func() {
+				tarball = bytes.Repeat([]byte("stemcell"), 1024)
+			})
+
+			download := func(server *rangeServer, config boshio.TransportConfig, times int) {
+				config.CACert = certificatePEM(server.Certificate())
+				config.Concurrency = 10
+
+				httpClient, err := boshio.NewHTTPClientWithConfig(server.URL, waitTime, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				client := boshio.NewClient(httpClient, &fakes.Bar{}, content.NewRanger(10), false)
+				location, err := os.MkdirTemp("", "")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(location)
+
+				for i := 0; i < times; i++ {
+					_, err = client.DownloadStemcell(boshio.Stemcell{
+						Name:    "some-stemcell",
+						Version: "1.0",
+						Regular: &boshio.Metadata{URL: server.URL + "/stemcell.tgz", SHA1: sha1Hex(tarball)},
+					}, location, false, boshio.Auth{})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+
+			It("reuses the connections across downloads", func() {
+				single := newRangeServer(tarball, false)
+				defer single.Close()
+				download(single, boshio.TransportConfig{}, 1)
+
+				twice := newRangeServer(tarball, false)
+				defer twice.Close()
+				download(twice, boshio.TransportConfig{}, 2)
+
+				Expect(single.Connections()).To(BeNumerically("<=", 11))
+				Expect(twice.Connections()).To(Equal(single.Connections()))
+			})
+
+			It("opens a connection per request when keep alives are disabled", func() {
+				server := newRangeServer(tarball, false)
+				defer server.Close()
+
+				download(server, boshio.TransportConfig{DisableKeepAlives: true}, 2)
+
+				// a HEAD and ten ranged requests per download
+				Expect(server.Connections()).To(BeEquivalentTo(22))
+			})
+
+			It("multiplexes the requests over HTTP/2 when enabled", func() {
+				server := newRangeServer(tarball, true)
+				defer server.Close()
+
+				download(server, boshio.TransportConfig{HTTP2: true}, 2)
+
+				Expect(server.Connections()).To(BeEquivalentTo(1))
+			})
+		})
+
+		Context("when a proxy is configured", func() {
+			var (
+				server *httptest.Server
+				p      *proxy
+			)
+
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusTeapot)
+				}))
+				p = newProxy(server.URL)
+			})
+
+			AfterEach(func() {
+				p.Close()
+				server.Close()
+			})
+
+			get := func(config boshio.TransportConfig) (*http.Response, error) {
+				client, err := boshio.NewHTTPClientWithConfig("http://bosh.test", waitTime, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				request, err := http.NewRequest("GET", "/api/v1/stemcells", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				return client.Do(request)
+			}
+
+			It("sends the requests through the proxy", func() {
+				response, err := get(boshio.TransportConfig{Proxy: p.URL()})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusTeapot))
+
+				Expect(p.Requests()).To(Equal([]string{"GET http://bosh.test/api/v1/stemcells"}))
+			})
+
+			It("authenticates with the credentials of the proxy url", func() {
+				p.Credentials = "some-user:some-password"
+				proxyURL := strings.Replace(p.URL(), "http://", "http://some-user:some-password@", 1)
+
+				response, err := get(boshio.TransportConfig{Proxy: proxyURL})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusTeapot))
+			})
+
+			It("connects directly to hosts matching no_proxy", func() {
+				_, err := get(boshio.TransportConfig{Proxy: p.URL(), NoProxy: []string{"example.com", ".test"}})
+				Expect(err).To(MatchError(ContainSubstring("bosh.test")))
+
+				Expect(p.Requests()).To(BeEmpty())
+			})
+
+			It("returns an error for an unsupported scheme", func() {
+				_, err := boshio.NewHTTPClientWithConfig("http://bosh.test", waitTime, boshio.TransportConfig{Proxy: "ftp://proxy.test"})
+				Expect(err).To(MatchError("unsupported proxy scheme 'ftp': must be one of http, https or socks5"))
+			})
+		})
+
 		Context("when an error occurs", func() {
+			Context("when the ca_cert cannot be parsed", func() {
+				It("returns an error", func() {
+					_, err := boshio.NewHTTPClientWithConfig("https://bosh.io", waitTime, boshio.TransportConfig{CACert: "not a cert"})
+					Expect(err).To(MatchError("failed to parse ca_cert"))
+				})
+			})
+
+			Context("when only the client_cert is provided", func() {
+				It("returns an error", func() {
+					clientCert, _, _ := generateClientCertificate()
+
+					_, err := boshio.NewHTTPClientWithConfig("https://bosh.io", waitTime, boshio.TransportConfig{ClientCert: clientCert})
+					Expect(err).To(MatchError("client_cert and client_key must be provided together"))
+				})
+			})
+
+			Context("when the client_key does not match the client_cert", func() {
+				It("returns an error", func() {
+					clientCert, _, _ := generateClientCertificate()
+					_, otherKey, _ := generateClientCertificate()
+
+					_, err := boshio.NewHTTPClientWithConfig("https://bosh.io", waitTime, boshio.TransportConfig{ClientCert: clientCert, ClientKey: otherKey})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse client_cert and client_key")))
+				})
+			})
+
 			Context("when the host cannot be parsed", func() {
 				It("returns an error", func() {
 					client := boshio.NewHTTPClient("%%%%%%", waitTime)
@@ -1,11 +1,17 @@
 package boshio_test
 
 import (
+	"encoding/base64"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/johannesboyne/gofakes3"
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
@@ -29,6 +35,7 @@ var (
 type server struct {
 	RedirectHandler         http.HandlerFunc
 	TarballHandler          http.HandlerFunc
+	SignatureHandler        http.HandlerFunc
 	LightAPIHandler         http.HandlerFunc
 	HeavyAPIHandler         http.HandlerFunc
 	HeavyAndLightAPIHandler http.HandlerFunc
@@ -40,6 +47,7 @@ type server struct {
 func (s *server) Start() {
 	s.mux.HandleFunc("/path/to/light-different-stemcell.tgz", boshioServer.TarballHandler)
 	s.mux.HandleFunc("/path/to/heavy-different-stemcell.tgz", boshioServer.TarballHandler)
+	s.mux.HandleFunc("/path/to/light-different-stemcell.tgz.sig", boshioServer.SignatureHandler)
 	s.mux.HandleFunc("/api/v1/stemcells/some-light-stemcell", boshioServer.LightAPIHandler)
 	s.mux.HandleFunc("/api/v1/stemcells/some-heavy-stemcell", boshioServer.HeavyAPIHandler)
 	s.mux.HandleFunc("/api/v1/stemcells/some-light-and-heavy-stemcell", boshioServer.HeavyAndLightAPIHandler)
@@ -67,6 +75,7 @@ var _ = BeforeEach(func() {
 	boshioServer = &server{
 		mux:                     router,
 		TarballHandler:          tarballHandler,
+		SignatureHandler:        http.NotFound,
 		LightAPIHandler:         lightAPIHandler,
 		HeavyAPIHandler:         heavyAPIHandler,
 		HeavyAndLightAPIHandler: heavyAndLightAPIHandler,
@@ -79,6 +88,169 @@ var _ = AfterEach(func() {
 	boshioServer.Stop()
 })
 
+// proxy is a stand-in for a forward proxy which records every request it
+// receives and forwards it to the target server.
+type proxy struct {
+	Credentials string
+
+	mutex    sync.Mutex
+	requests []string
+	s        *httptest.Server
+}
+
+func newProxy(target string) *proxy {
+	targetURL, err := url.Parse(target)
+	Expect(err).NotTo(HaveOccurred())
+
+	p := &proxy{}
+	forward := httputil.NewSingleHostReverseProxy(targetURL)
+
+	p.s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p.mutex.Lock()
+		p.requests = append(p.requests, req.Method+" "+req.URL.String())
+		p.mutex.Unlock()
+
+		// proxied requests carry the absolute url of the target
+		if !req.URL.IsAbs() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if p.Credentials != "" {
+			expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(p.Credentials))
+			if req.Header.Get("Proxy-Authorization") != expected {
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+		}
+
+		forward.ServeHTTP(w, req)
+	}))
+
+	return p
+}
+
+func (p *proxy) URL() string {
+	return p.s.URL
+}
+
+func (p *proxy) Requests() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string{}, p.requests...)
+}
+
+func (p *proxy) Close() {
+	p.s.Close()
+}
+
+// socksProxy is a stand-in for a socks5 proxy which records the address of
+// every connection it is asked for and connects it to the target server.
+type socksProxy struct {
+	mutex     sync.Mutex
+	addresses []string
+	listener  net.Listener
+}
+
+func newSocksProxy(target string) *socksProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+
+	p := &socksProxy{listener: listener}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn, target)
+		}
+	}()
+
+	return p
+}
+
+func (p *socksProxy) serve(conn net.Conn, target string) {
+	defer conn.Close()
+
+	// greeting: version, number of methods and the methods, answered with
+	// the no authentication method
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 0})
+
+	// request: version, command, reserved, address type, address and port
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+
+	var host string
+	switch request[3] {
+	case 1, 4:
+		ip := make([]byte, net.IPv4len)
+		if request[3] == 4 {
+			ip = make([]byte, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		return
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+
+	p.mutex.Lock()
+	p.addresses = append(p.addresses, net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))))
+	p.mutex.Unlock()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func (p *socksProxy) URL() string {
+	return "socks5://" + p.listener.Addr().String()
+}
+
+func (p *socksProxy) Addresses() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string{}, p.addresses...)
+}
+
+func (p *socksProxy) Close() {
+	p.listener.Close()
+}
+
 func tarballHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method == "HEAD" {
 		w.Header().Add("Content-Length", "100")
@@ -0,0 +1,147 @@
+package boshio
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+const (
+	SignatureTypePGP    = "pgp"
+	SignatureTypeCosign = "cosign"
+)
+
+// SignatureConfig configures the verification of the detached signature
+// published next to the stemcell tarball.
+type SignatureConfig struct {
+	Type      string `json:"type"`
+	PublicKey string `json:"public_key"`
+	Suffix    string `json:"suffix"`
+}
+
+// SignatureVerifier verifies stemcell tarballs against their detached
+// signatures.
+type SignatureVerifier struct {
+	suffix string
+	verify func(tarball io.Reader, signature []byte) error
+}
+
+func NewSignatureVerifier(config SignatureConfig) (*SignatureVerifier, error) {
+	if config.PublicKey == "" {
+		return nil, errors.New("verify_signature requires a public_key")
+	}
+
+	var (
+		verifier SignatureVerifier
+		err      error
+	)
+
+	switch config.Type {
+	case SignatureTypePGP:
+		verifier.suffix = ".asc"
+		verifier.verify, err = pgpVerifier(config.PublicKey)
+	case SignatureTypeCosign:
+		verifier.suffix = ".sig"
+		verifier.verify, err = cosignVerifier(config.PublicKey)
+	default:
+		return nil, fmt.Errorf("unknown signature type '%s': must be one of '%s' or '%s'", config.Type, SignatureTypePGP, SignatureTypeCosign)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Suffix != "" {
+		verifier.suffix = config.Suffix
+	}
+
+	return &verifier, nil
+}
+
+// SignatureURL returns the URL of the signature published next to the tarball.
+func (v *SignatureVerifier) SignatureURL(tarballURL string) string {
+	return tarballURL + v.suffix
+}
+
+// Verify checks the signature against the contents of the tarball.
+func (v *SignatureVerifier) Verify(tarball io.Reader, signature []byte) error {
+	err := v.verify(tarball, signature)
+	if err != nil {
+		return fmt.Errorf("failed to verify stemcell signature: %s", err)
+	}
+	return nil
+}
+
+func pgpVerifier(publicKey string) (func(io.Reader, []byte) error, error) {
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pgp public key: %s", err)
+	}
+
+	return func(tarball io.Reader, signature []byte) error {
+		// signatures may be published ascii armored or binary
+		if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+			_, err := openpgp.CheckArmoredDetachedSignature(keyRing, tarball, bytes.NewReader(signature), nil)
+			return err
+		}
+
+		_, err := openpgp.CheckDetachedSignature(keyRing, tarball, bytes.NewReader(signature), nil)
+		return err
+	}, nil
+}
+
+// cosignVerifier verifies the base64 encoded signatures of the sha256 of a
+// blob, as produced by `cosign sign-blob` with a key pair.
+func cosignVerifier(publicKey string) (func(io.Reader, []byte) error, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return nil, errors.New("failed to parse cosign public key: no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cosign public key: %s", err)
+	}
+
+	var verifyDigest func(digest []byte, signature []byte) error
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		verifyDigest = func(digest []byte, signature []byte) error {
+			if !ecdsa.VerifyASN1(k, digest, signature) {
+				return errors.New("invalid ecdsa signature")
+			}
+			return nil
+		}
+	case *rsa.PublicKey:
+		verifyDigest = func(digest []byte, signature []byte) error {
+			return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, signature)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported cosign public key type %T: must be ecdsa or rsa", key)
+	}
+
+	return func(tarball io.Reader, signature []byte) error {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+		if err != nil {
+			return fmt.Errorf("failed to decode signature: %s", err)
+		}
+
+		digest := sha256.New()
+		_, err = io.Copy(digest, tarball)
+		if err != nil {
+			return err
+		}
+
+		return verifyDigest(digest.Sum(nil), decoded)
+	}, nil
+}
@@ -0,0 +1,182 @@
+package boshio_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const signedContent = "some stemcell tarball"
+
+// pgpKey returns an armored public key and a function signing with its
+// private key.
+func pgpKey() (string, func(content string, armored bool) []byte) {
+	entity, err := openpgp.NewEntity("stemcell builder", "", "builder@example.com", nil)
+	Expect(err).NotTo(HaveOccurred())
+
+	var publicKey bytes.Buffer
+	armorWriter, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(entity.Serialize(armorWriter)).To(Succeed())
+	Expect(armorWriter.Close()).To(Succeed())
+
+	return publicKey.String(), func(content string, armored bool) []byte {
+		var signature bytes.Buffer
+		if armored {
+			Expect(openpgp.ArmoredDetachSign(&signature, entity, strings.NewReader(content), nil)).To(Succeed())
+		} else {
+			Expect(openpgp.DetachSign(&signature, entity, strings.NewReader(content), nil)).To(Succeed())
+		}
+		return signature.Bytes()
+	}
+}
+
+// cosignKey returns a PEM public key and a function producing base64 encoded
+// signatures of the sha256 of a blob, like `cosign sign-blob`.
+func cosignKey() (string, func(content string) []byte) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	return string(publicKey), func(content string) []byte {
+		digest := sha256.Sum256([]byte(content))
+		signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+		Expect(err).NotTo(HaveOccurred())
+		return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+	}
+}
+
+var _ = Describe("SignatureVerifier", func() {
+	Context("with a pgp key", func() {
+		var (
+			verifier *boshio.SignatureVerifier
+			sign     func(string, bool) []byte
+		)
+
+		BeforeEach(func() {
+			var publicKey string
+			publicKey, sign = pgpKey()
+
+			var err error
+			verifier, err = boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "pgp", PublicKey: publicKey})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("uses the .asc signature next to the tarball", func() {
+			Expect(verifier.SignatureURL("https://example.com/stemcell.tgz")).To(Equal("https://example.com/stemcell.tgz.asc"))
+		})
+
+		It("verifies armored and binary signatures", func() {
+			Expect(verifier.Verify(strings.NewReader(signedContent), sign(signedContent, true))).To(Succeed())
+			Expect(verifier.Verify(strings.NewReader(signedContent), sign(signedContent, false))).To(Succeed())
+		})
+
+		It("rejects a signature of different content", func() {
+			err := verifier.Verify(strings.NewReader(signedContent), sign("other content", true))
+			Expect(err).To(MatchError(ContainSubstring("failed to verify stemcell signature")))
+		})
+
+		It("rejects a signature made with another key", func() {
+			_, otherSign := pgpKey()
+
+			err := verifier.Verify(strings.NewReader(signedContent), otherSign(signedContent, true))
+			Expect(err).To(MatchError(ContainSubstring("failed to verify stemcell signature")))
+		})
+	})
+
+	Context("with a cosign key", func() {
+		var (
+			verifier *boshio.SignatureVerifier
+			sign     func(string) []byte
+		)
+
+		BeforeEach(func() {
+			var publicKey string
+			publicKey, sign = cosignKey()
+
+			var err error
+			verifier, err = boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "cosign", PublicKey: publicKey})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("uses the .sig signature next to the tarball", func() {
+			Expect(verifier.SignatureURL("https://example.com/stemcell.tgz")).To(Equal("https://example.com/stemcell.tgz.sig"))
+		})
+
+		It("verifies the signature", func() {
+			Expect(verifier.Verify(strings.NewReader(signedContent), sign(signedContent))).To(Succeed())
+		})
+
+		It("rejects a signature of different content", func() {
+			err := verifier.Verify(strings.NewReader(signedContent), sign("other content"))
+			Expect(err).To(MatchError("failed to verify stemcell signature: invalid ecdsa signature"))
+		})
+
+		It("rejects a signature which is not base64 encoded", func() {
+			err := verifier.Verify(strings.NewReader(signedContent), []byte("%%%%"))
+			Expect(err).To(MatchError(ContainSubstring("failed to decode signature")))
+		})
+
+		It("verifies signatures made with rsa keys", func() {
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+
+			der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			verifier, err := boshio.NewSignatureVerifier(boshio.SignatureConfig{
+				Type:      "cosign",
+				PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			digest := sha256.Sum256([]byte(signedContent))
+			signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(verifier.Verify(strings.NewReader(signedContent), []byte(base64.StdEncoding.EncodeToString(signature)))).To(Succeed())
+		})
+	})
+
+	It("uses a configured suffix", func() {
+		publicKey, _ := cosignKey()
+
+		verifier, err := boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "cosign", PublicKey: publicKey, Suffix: ".cosign.sig"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.SignatureURL("https://example.com/stemcell.tgz")).To(Equal("https://example.com/stemcell.tgz.cosign.sig"))
+	})
+
+	It("returns an error for an unknown type", func() {
+		_, err := boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "x509", PublicKey: "some-key"})
+		Expect(err).To(MatchError("unknown signature type 'x509': must be one of 'pgp' or 'cosign'"))
+	})
+
+	It("returns an error without a public key", func() {
+		_, err := boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "pgp"})
+		Expect(err).To(MatchError("verify_signature requires a public_key"))
+	})
+
+	It("returns an error for a malformed public key", func() {
+		_, err := boshio.NewSignatureVerifier(boshio.SignatureConfig{Type: "cosign", PublicKey: "not a key"})
+		Expect(err).To(MatchError("failed to parse cosign public key: no PEM block found"))
+	})
+})
@@ -0,0 +1,116 @@
+package boshio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	lightNamePrefix = "light-"
+	boshNamePrefix  = "bosh-"
+	fipsComponent   = "fips"
+)
+
+// StemcellName is the structured form of a stemcell name such as
+// bosh-aws-xen-hvm-ubuntu-jammy-go_agent, or light-bosh-google-kvm-ubuntu-jammy-fips-go_agent
+// for the light and FIPS variants.
+type StemcellName struct {
+	Light      bool
+	IaaS       string
+	Hypervisor string
+	OS         string
+	FIPS       bool
+	Agent      string
+}
+
+// ParseStemcellName splits a stemcell name into its IaaS, hypervisor,
+// operating system and agent.
+func ParseStemcellName(name string) (StemcellName, error) {
+	var parsed StemcellName
+
+	remainder := name
+	if strings.HasPrefix(remainder, lightNamePrefix) {
+		parsed.Light = true
+		remainder = strings.TrimPrefix(remainder, lightNamePrefix)
+	}
+
+	if !strings.HasPrefix(remainder, boshNamePrefix) {
+		return StemcellName{}, fmt.Errorf("failed to parse stemcell name '%s': expected it to start with '%s'", name, boshNamePrefix)
+	}
+	parts := strings.Split(strings.TrimPrefix(remainder, boshNamePrefix), "-")
+
+	if len(parts) < 4 {
+		return StemcellName{}, fmt.Errorf("failed to parse stemcell name '%s': expected an IaaS, hypervisor, operating system and agent", name)
+	}
+
+	parsed.Agent = parts[len(parts)-1]
+	parts = parts[:len(parts)-1]
+
+	if parts[len(parts)-1] == fipsComponent {
+		parsed.FIPS = true
+		parts = parts[:len(parts)-1]
+	}
+
+	parsed.IaaS = parts[0]
+
+	// the operating system is the trailing ubuntu-<codename>, centos-<version>
+	// or windows<version>, and the hypervisor everything in between
+	osStart := -1
+	switch last := len(parts) - 1; {
+	case last >= 3 && (parts[last-1] == "ubuntu" || parts[last-1] == "centos"):
+		osStart = last - 1
+	case last >= 2 && strings.HasPrefix(parts[last], "windows"):
+		osStart = last
+	}
+	if osStart < 0 {
+		return StemcellName{}, fmt.Errorf("failed to determine the operating system of stemcell '%s'", name)
+	}
+
+	parsed.Hypervisor = strings.Join(parts[1:osStart], "-")
+	parsed.OS = strings.Join(parts[osStart:], "-")
+
+	return parsed, nil
+}
+
+// String formats the name as published on bosh.io.
+func (n StemcellName) String() string {
+	parts := []string{n.IaaS, n.Hypervisor, n.OS}
+	if n.FIPS {
+		parts = append(parts, fipsComponent)
+	}
+	parts = append(parts, n.Agent)
+
+	name := boshNamePrefix + strings.Join(parts, "-")
+	if n.Light {
+		name = lightNamePrefix + name
+	}
+	return name
+}
+
+// SelectStemcellName returns the one name out of the published names with the
+// given IaaS and operating system. An empty IaaS or operating system matches
+// any, while the light and FIPS variants are only selected by their full name.
+func SelectStemcellName(names []string, iaas string, os string) (string, error) {
+	var matches []string
+	for _, name := range names {
+		parsed, err := ParseStemcellName(name)
+		if err != nil || parsed.Light || parsed.FIPS {
+			continue
+		}
+
+		if (iaas == "" || parsed.IaaS == iaas) && (os == "" || parsed.OS == os) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no stemcell is published on bosh.io for iaas '%s' and os '%s'", iaas, os)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("several stemcells are published on bosh.io for iaas '%s' and os '%s': %s, set the name instead", iaas, os, strings.Join(matches, ", "))
+	}
+}
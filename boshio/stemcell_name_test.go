@@ -0,0 +1,77 @@
+package boshio_test
+
+import (
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StemcellName", func() {
+	DescribeTable("parses and formats names",
+		func(name string, expected boshio.StemcellName) {
+			parsed, err := boshio.ParseStemcellName(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed).To(Equal(expected))
+			Expect(parsed.String()).To(Equal(name))
+		},
+		Entry("a linux stemcell", "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", boshio.StemcellName{
+			IaaS: "aws", Hypervisor: "xen-hvm", OS: "ubuntu-jammy", Agent: "go_agent",
+		}),
+		Entry("a fips stemcell", "bosh-google-kvm-ubuntu-jammy-fips-go_agent", boshio.StemcellName{
+			IaaS: "google", Hypervisor: "kvm", OS: "ubuntu-jammy", FIPS: true, Agent: "go_agent",
+		}),
+		Entry("a light stemcell", "light-bosh-aws-xen-hvm-ubuntu-jammy-go_agent", boshio.StemcellName{
+			Light: true, IaaS: "aws", Hypervisor: "xen-hvm", OS: "ubuntu-jammy", Agent: "go_agent",
+		}),
+		Entry("a windows stemcell", "bosh-azure-hyperv-windows2019-go_agent", boshio.StemcellName{
+			IaaS: "azure", Hypervisor: "hyperv", OS: "windows2019", Agent: "go_agent",
+		}),
+		Entry("a centos stemcell", "bosh-vsphere-esxi-centos-7-go_agent", boshio.StemcellName{
+			IaaS: "vsphere", Hypervisor: "esxi", OS: "centos-7", Agent: "go_agent",
+		}),
+	)
+
+	DescribeTable("returns an error for names that do not follow the convention",
+		func(name string, expected string) {
+			_, err := boshio.ParseStemcellName(name)
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("without the bosh prefix", "some-stemcell", "failed to parse stemcell name 'some-stemcell': expected it to start with 'bosh-'"),
+		Entry("with too few components", "bosh-aws-go_agent", "failed to parse stemcell name 'bosh-aws-go_agent': expected an IaaS, hypervisor, operating system and agent"),
+		Entry("without a known operating system", "bosh-aws-xen-hvm-plan9-go_agent", "failed to determine the operating system of stemcell 'bosh-aws-xen-hvm-plan9-go_agent'"),
+		Entry("without a hypervisor", "bosh-aws-ubuntu-jammy-go_agent", "failed to determine the operating system of stemcell 'bosh-aws-ubuntu-jammy-go_agent'"),
+	)
+
+	Describe("SelectStemcellName", func() {
+		var names []string
+
+		BeforeEach(func() {
+			names = []string{
+				"bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+				"bosh-aws-xen-hvm-ubuntu-jammy-fips-go_agent",
+				"bosh-aws-xen-hvm-ubuntu-noble-go_agent",
+				"bosh-google-kvm-ubuntu-jammy-go_agent",
+				"some-stemcell",
+			}
+		})
+
+		It("selects the name for the iaas and os", func() {
+			Expect(boshio.SelectStemcellName(names, "aws", "ubuntu-jammy")).To(Equal("bosh-aws-xen-hvm-ubuntu-jammy-go_agent"))
+		})
+
+		It("selects the name by a single field when it is unambiguous", func() {
+			Expect(boshio.SelectStemcellName(names, "google", "")).To(Equal("bosh-google-kvm-ubuntu-jammy-go_agent"))
+		})
+
+		It("returns an error when several names match", func() {
+			_, err := boshio.SelectStemcellName(names, "", "ubuntu-jammy")
+			Expect(err).To(MatchError("several stemcells are published on bosh.io for iaas '' and os 'ubuntu-jammy': bosh-aws-xen-hvm-ubuntu-jammy-go_agent, bosh-google-kvm-ubuntu-jammy-go_agent, set the name instead"))
+		})
+
+		It("returns an error when no name matches", func() {
+			_, err := boshio.SelectStemcellName(names, "azure", "ubuntu-jammy")
+			Expect(err).To(MatchError("no stemcell is published on bosh.io for iaas 'azure' and os 'ubuntu-jammy'"))
+		})
+	})
+})
@@ -1,5 +1,16 @@
 package boshio
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+const (
+	FlavorLight   = "light"
+	FlavorRegular = "regular"
+)
+
 type Stemcell struct {
 	Name         string
 	Version      string
@@ -16,6 +27,73 @@ type Metadata struct {
 	SHA256 string
 }
 
+// Validate ensures the stemcell has the fields needed to check and fetch it.
+func (s Stemcell) Validate() error {
+	if s.Version == "" {
+		return errors.New("missing version")
+	}
+
+	if s.Light == nil && s.Regular == nil {
+		return errors.New("neither a light nor a regular flavor is published")
+	}
+
+	for _, flavor := range []struct {
+		name     string
+		metadata *Metadata
+	}{{FlavorLight, s.Light}, {FlavorRegular, s.Regular}} {
+		if flavor.metadata == nil {
+			continue
+		}
+		if flavor.metadata.URL == "" {
+			return fmt.Errorf("%s flavor is missing its url", flavor.name)
+		}
+		if flavor.metadata.SHA1 == "" && flavor.metadata.SHA256 == "" {
+			return fmt.Errorf("%s flavor is missing its sha1 and sha256", flavor.name)
+		}
+		if flavor.metadata.Size < 0 {
+			return fmt.Errorf("%s flavor has a negative size", flavor.name)
+		}
+	}
+
+	return nil
+}
+
+// InvalidStemcell is a published entry which cannot be checked or fetched, and
+// is therefore left out of the stemcells.
+type InvalidStemcell struct {
+	Index   int
+	Version string
+	Err     error
+}
+
+func (i InvalidStemcell) Error() string {
+	if i.Version == "" {
+		return fmt.Sprintf("malformed stemcell at index %d: %s", i.Index, i.Err)
+	}
+	return fmt.Sprintf("malformed stemcell at index %d (version '%s'): %s", i.Index, i.Version, i.Err)
+}
+
+type InvalidStemcells []InvalidStemcell
+
+// FindByVersion returns the invalid entry published for the version.
+func (s InvalidStemcells) FindByVersion(version string) (InvalidStemcell, bool) {
+	for _, invalid := range s {
+		if invalid.Version == version {
+			return invalid, true
+		}
+	}
+	return InvalidStemcell{}, false
+}
+
+// Provider returns the host serving the stemcell tarball.
+func (m Metadata) Provider() string {
+	parsedURL, err := url.Parse(m.URL)
+	if err != nil {
+		return ""
+	}
+	return parsedURL.Host
+}
+
 func (s Stemcell) Details() Metadata {
 	if s.Light != nil && s.ForceRegular == false {
 		return *s.Light
@@ -24,6 +102,63 @@ func (s Stemcell) Details() Metadata {
 	return *s.Regular
 }
 
+// OS returns the operating system of the stemcell as used in BOSH manifests,
+// e.g. ubuntu-jammy for bosh-aws-xen-hvm-ubuntu-jammy-go_agent.
+func (s Stemcell) OS() (string, error) {
+	name, err := ParseStemcellName(s.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the operating system of stemcell '%s'", s.Name)
+	}
+
+	return name.OS, nil
+}
+
+// Flavor returns the flavor of the stemcell returned by Details.
+func (s Stemcell) Flavor() string {
+	if s.Light != nil && s.ForceRegular == false {
+		return FlavorLight
+	}
+
+	return FlavorRegular
+}
+
+// WithFlavor returns a copy of the stemcell whose Details are those of the
+// requested flavor.
+func (s Stemcell) WithFlavor(flavor string) (Stemcell, error) {
+	switch flavor {
+	case FlavorLight:
+		if s.Light == nil {
+			return Stemcell{}, fmt.Errorf("stemcell %s version %s has no %s flavor", s.Name, s.Version, flavor)
+		}
+		s.ForceRegular = false
+	case FlavorRegular:
+		if s.Regular == nil {
+			return Stemcell{}, fmt.Errorf("stemcell %s version %s has no %s flavor", s.Name, s.Version, flavor)
+		}
+		s.ForceRegular = true
+	default:
+		return Stemcell{}, fmt.Errorf("unknown stemcell flavor '%s': must be one of '%s' or '%s'", flavor, FlavorLight, FlavorRegular)
+	}
+
+	return s, nil
+}
+
+// VerifyChecksum ensures that the stemcell still has the checksum that was
+// recorded in the Concourse version. Empty checksums are not verified.
+func (s Stemcell) VerifyChecksum(sha1 string, sha256 string) error {
+	details := s.Details()
+
+	if sha256 != "" && details.SHA256 != sha256 {
+		return fmt.Errorf("stemcell %s version %s with sha256 %s no longer exists upstream (found sha256 %s): it may have been republished, run check again to pick up the new version", s.Name, s.Version, sha256, details.SHA256)
+	}
+
+	if sha1 != "" && details.SHA1 != sha1 {
+		return fmt.Errorf("stemcell %s version %s with sha1 %s no longer exists upstream (found sha1 %s): it may have been republished, run check again to pick up the new version", s.Name, s.Version, sha1, details.SHA1)
+	}
+
+	return nil
+}
+
 type Stemcells []Stemcell
 
 func (s Stemcells) FindStemcellByVersion(version string) (Stemcell, bool) {
@@ -52,6 +187,64 @@ func (s Stemcells) FilterByType() Stemcells {
 	}
 }
 
+// FilterByFlavors keeps only the stemcells for which every one of the given
+// flavors has been published. bosh.io publishes the light and regular
+// variants of a version independently, sometimes several hours apart.
+func (s Stemcells) FilterByFlavors(flavors []string) (Stemcells, error) {
+	for _, flavor := range flavors {
+		if flavor != FlavorLight && flavor != FlavorRegular {
+			return nil, fmt.Errorf("unknown stemcell flavor '%s': must be one of '%s' or '%s'", flavor, FlavorLight, FlavorRegular)
+		}
+	}
+
+	filterFunc := func(stemcell Stemcell) bool {
+		for _, flavor := range flavors {
+			if flavor == FlavorLight && stemcell.Light == nil {
+				return false
+			}
+			if flavor == FlavorRegular && stemcell.Regular == nil {
+				return false
+			}
+		}
+		return true
+	}
+	return s.filterStemcells(filterFunc), nil
+}
+
+// CommonVersions keeps only the stemcells whose version is also available in
+// every one of the other lists.
+func (s Stemcells) CommonVersions(others ...Stemcells) Stemcells {
+	filterFunc := func(stemcell Stemcell) bool {
+		for _, other := range others {
+			if _, ok := other.FindStemcellByVersion(stemcell.Version); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return s.filterStemcells(filterFunc)
+}
+
+// HasLatestFlavor reports whether the latest versions alone can be filtered
+// by type. A latest version without a light flavor may still be waiting for
+// it, which only the older versions of the stemcell tell.
+func (s Stemcells) HasLatestFlavor() bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, stemcell := range s {
+		if stemcell.Light == nil && !stemcell.ForceRegular {
+			return false
+		}
+		if stemcell.Regular == nil && stemcell.ForceRegular {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (s Stemcells) lightStemcellsOnly() Stemcells {
 	filterFunc := func(stemcell Stemcell) bool {
 		return stemcell.Light != nil
@@ -115,6 +115,183 @@ var _ = Describe("Stemcells", func() {
 		})
 	})
 
+	Describe("OS", func() {
+		It("returns the operating system of linux stemcells", func() {
+			stemcell := boshio.Stemcell{Name: "bosh-aws-xen-hvm-ubuntu-jammy-go_agent"}
+			Expect(stemcell.OS()).To(Equal("ubuntu-jammy"))
+		})
+
+		It("ignores the fips suffix", func() {
+			stemcell := boshio.Stemcell{Name: "bosh-google-kvm-ubuntu-bionic-fips-go_agent"}
+			Expect(stemcell.OS()).To(Equal("ubuntu-bionic"))
+		})
+
+		It("returns the operating system of windows stemcells", func() {
+			stemcell := boshio.Stemcell{Name: "bosh-azure-hyperv-windows2019-go_agent"}
+			Expect(stemcell.OS()).To(Equal("windows2019"))
+		})
+
+		Context("when the operating system cannot be determined", func() {
+			It("returns an error", func() {
+				_, err := boshio.Stemcell{Name: "some-stemcell"}.OS()
+				Expect(err).To(MatchError("failed to determine the operating system of stemcell 'some-stemcell'"))
+			})
+		})
+	})
+
+	Describe("Flavor", func() {
+		It("returns light when the light stemcell is used", func() {
+			stemcell := boshio.Stemcell{Light: &boshio.Metadata{}, Regular: &boshio.Metadata{}}
+			Expect(stemcell.Flavor()).To(Equal("light"))
+		})
+
+		It("returns regular when force_regular is true", func() {
+			stemcell := boshio.Stemcell{Light: &boshio.Metadata{}, Regular: &boshio.Metadata{}, ForceRegular: true}
+			Expect(stemcell.Flavor()).To(Equal("regular"))
+		})
+
+		It("returns regular when only the regular stemcell is available", func() {
+			stemcell := boshio.Stemcell{Regular: &boshio.Metadata{}}
+			Expect(stemcell.Flavor()).To(Equal("regular"))
+		})
+	})
+
+	Describe("WithFlavor", func() {
+		var stemcell boshio.Stemcell
+
+		BeforeEach(func() {
+			stemcell = boshio.Stemcell{
+				Name:    "some-stemcell",
+				Version: "111.1",
+				Light:   &boshio.Metadata{URL: "fake-url-light"},
+				Regular: &boshio.Metadata{URL: "fake-url-regular"},
+			}
+		})
+
+		It("returns the light flavor", func() {
+			stemcell.ForceRegular = true
+
+			light, err := stemcell.WithFlavor("light")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(light.Details().URL).To(Equal("fake-url-light"))
+		})
+
+		It("returns the regular flavor", func() {
+			regular, err := stemcell.WithFlavor("regular")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(regular.Details().URL).To(Equal("fake-url-regular"))
+		})
+
+		It("does not modify the original stemcell", func() {
+			_, err := stemcell.WithFlavor("regular")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stemcell.Details().URL).To(Equal("fake-url-light"))
+		})
+
+		Context("when the flavor is not available", func() {
+			It("returns an error", func() {
+				stemcell.Light = nil
+
+				_, err := stemcell.WithFlavor("light")
+				Expect(err).To(MatchError("stemcell some-stemcell version 111.1 has no light flavor"))
+			})
+		})
+
+		Context("when the flavor is unknown", func() {
+			It("returns an error", func() {
+				_, err := stemcell.WithFlavor("heavy")
+				Expect(err).To(MatchError("unknown stemcell flavor 'heavy': must be one of 'light' or 'regular'"))
+			})
+		})
+	})
+
+	Describe("VerifyChecksum", func() {
+		var stemcell boshio.Stemcell
+
+		BeforeEach(func() {
+			stemcell = boshio.Stemcell{
+				Name:    "some-stemcell",
+				Version: "111.1",
+				Regular: &boshio.Metadata{
+					SHA1:   "fake-sha1",
+					SHA256: "fake-sha256",
+				},
+			}
+		})
+
+		It("succeeds when the checksums match", func() {
+			Expect(stemcell.VerifyChecksum("fake-sha1", "fake-sha256")).To(Succeed())
+		})
+
+		It("succeeds when no checksums are provided", func() {
+			Expect(stemcell.VerifyChecksum("", "")).To(Succeed())
+		})
+
+		Context("when the sha256 no longer matches", func() {
+			It("returns an error", func() {
+				err := stemcell.VerifyChecksum("", "old-sha256")
+				Expect(err).To(MatchError(ContainSubstring("stemcell some-stemcell version 111.1 with sha256 old-sha256 no longer exists upstream (found sha256 fake-sha256)")))
+			})
+		})
+
+		Context("when the sha1 no longer matches", func() {
+			It("returns an error", func() {
+				err := stemcell.VerifyChecksum("old-sha1", "")
+				Expect(err).To(MatchError(ContainSubstring("stemcell some-stemcell version 111.1 with sha1 old-sha1 no longer exists upstream (found sha1 fake-sha1)")))
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		var stemcell boshio.Stemcell
+
+		BeforeEach(func() {
+			stemcell = boshio.Stemcell{
+				Name:    "some-stemcell",
+				Version: "1.0",
+				Light:   &boshio.Metadata{URL: "https://example.com/light.tgz", SHA1: "1111"},
+				Regular: &boshio.Metadata{URL: "https://example.com/regular.tgz", SHA256: "2222"},
+			}
+		})
+
+		It("succeeds for a complete stemcell", func() {
+			Expect(stemcell.Validate()).To(Succeed())
+		})
+
+		It("returns an error without a version", func() {
+			stemcell.Version = ""
+			Expect(stemcell.Validate()).To(MatchError("missing version"))
+		})
+
+		It("returns an error without any flavor", func() {
+			stemcell.Light = nil
+			stemcell.Regular = nil
+			Expect(stemcell.Validate()).To(MatchError("neither a light nor a regular flavor is published"))
+		})
+
+		It("returns an error when a flavor has no checksum", func() {
+			stemcell.Regular.SHA256 = ""
+			Expect(stemcell.Validate()).To(MatchError("regular flavor is missing its sha1 and sha256"))
+		})
+
+		It("returns an error when a flavor has a negative size", func() {
+			stemcell.Light.Size = -1
+			Expect(stemcell.Validate()).To(MatchError("light flavor has a negative size"))
+		})
+	})
+
+	Describe("Provider", func() {
+		It("returns the host serving the tarball", func() {
+			metadata := boshio.Metadata{URL: "https://storage.googleapis.com/bosh-core-stemcells/stemcell.tgz"}
+			Expect(metadata.Provider()).To(Equal("storage.googleapis.com"))
+		})
+
+		It("returns an empty string when the url cannot be parsed", func() {
+			metadata := boshio.Metadata{URL: "%%%%"}
+			Expect(metadata.Provider()).To(BeEmpty())
+		})
+	})
+
 	Describe("FindStemcellByVersion", func() {
 		var stemcellList boshio.Stemcells
 
@@ -241,4 +418,171 @@ var _ = Describe("Stemcells", func() {
 			})
 		})
 	})
+
+	Describe("HasLatestFlavor", func() {
+		It("is true when every stemcell has a light flavor", func() {
+			stemcellList := boshio.Stemcells{
+				{Version: "2", Light: &boshio.Metadata{}, Regular: &boshio.Metadata{}},
+				{Version: "1", Light: &boshio.Metadata{}},
+			}
+			Expect(stemcellList.HasLatestFlavor()).To(BeTrue())
+		})
+
+		It("is false when a stemcell may still be waiting for its light flavor", func() {
+			stemcellList := boshio.Stemcells{
+				{Version: "2", Regular: &boshio.Metadata{}},
+				{Version: "1", Light: &boshio.Metadata{}},
+			}
+			Expect(stemcellList.HasLatestFlavor()).To(BeFalse())
+		})
+
+		It("only needs a regular flavor when force_regular is true", func() {
+			stemcellList := boshio.Stemcells{
+				{Version: "2", Regular: &boshio.Metadata{}, ForceRegular: true},
+			}
+			Expect(stemcellList.HasLatestFlavor()).To(BeTrue())
+
+			stemcellList = boshio.Stemcells{
+				{Version: "2", Light: &boshio.Metadata{}, ForceRegular: true},
+			}
+			Expect(stemcellList.HasLatestFlavor()).To(BeFalse())
+		})
+
+		It("is false when there are no stemcells", func() {
+			Expect(boshio.Stemcells{}.HasLatestFlavor()).To(BeFalse())
+		})
+	})
+
+	Describe("FilterByFlavors", func() {
+		var stemcellList boshio.Stemcells
+
+		BeforeEach(func() {
+			stemcellList = boshio.Stemcells{
+				{
+					Version: "3",
+					Regular: &boshio.Metadata{},
+				},
+				{
+					Version: "2",
+					Light:   &boshio.Metadata{},
+				},
+				{
+					Version: "1",
+					Regular: &boshio.Metadata{},
+					Light:   &boshio.Metadata{},
+				},
+			}
+		})
+
+		It("returns only the stemcells providing every flavor", func() {
+			filteredStemcells, err := stemcellList.FilterByFlavors([]string{"light", "regular"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filteredStemcells).To(Equal(boshio.Stemcells{
+				{
+					Version: "1",
+					Regular: &boshio.Metadata{},
+					Light:   &boshio.Metadata{},
+				},
+			}))
+		})
+
+		It("returns only the stemcells providing a single flavor", func() {
+			filteredStemcells, err := stemcellList.FilterByFlavors([]string{"regular"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filteredStemcells).To(HaveLen(2))
+			Expect(filteredStemcells[0].Version).To(Equal("3"))
+			Expect(filteredStemcells[1].Version).To(Equal("1"))
+		})
+
+		It("returns all stemcells when no flavors are required", func() {
+			filteredStemcells, err := stemcellList.FilterByFlavors(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filteredStemcells).To(Equal(stemcellList))
+		})
+
+		Context("when the flavors of a version are published at different times", func() {
+			var published boshio.Stemcells
+
+			BeforeEach(func() {
+				published = boshio.Stemcells{
+					{Version: "2", Regular: &boshio.Metadata{}, Light: &boshio.Metadata{}},
+				}
+			})
+
+			It("waits for the light flavor published after the regular one", func() {
+				published = append(boshio.Stemcells{{Version: "3", Regular: &boshio.Metadata{}}}, published...)
+
+				filteredStemcells, err := published.FilterByFlavors([]string{"light", "regular"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filteredStemcells).To(HaveLen(1))
+				Expect(filteredStemcells[0].Version).To(Equal("2"))
+
+				published[0].Light = &boshio.Metadata{}
+
+				filteredStemcells, err = published.FilterByFlavors([]string{"light", "regular"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filteredStemcells).To(HaveLen(2))
+				Expect(filteredStemcells[0].Version).To(Equal("3"))
+			})
+
+			It("waits for the regular flavor published after the light one", func() {
+				published = append(boshio.Stemcells{{Version: "3", Light: &boshio.Metadata{}}}, published...)
+
+				filteredStemcells, err := published.FilterByType().FilterByFlavors([]string{"light", "regular"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filteredStemcells).To(HaveLen(1))
+				Expect(filteredStemcells[0].Version).To(Equal("2"))
+
+				published[0].Regular = &boshio.Metadata{}
+
+				filteredStemcells, err = published.FilterByType().FilterByFlavors([]string{"light", "regular"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filteredStemcells).To(HaveLen(2))
+				Expect(filteredStemcells[0].Version).To(Equal("3"))
+			})
+		})
+
+		Context("when a flavor is unknown", func() {
+			It("returns an error", func() {
+				_, err := stemcellList.FilterByFlavors([]string{"heavy"})
+				Expect(err).To(MatchError("unknown stemcell flavor 'heavy': must be one of 'light' or 'regular'"))
+			})
+		})
+	})
+
+	Describe("CommonVersions", func() {
+		var aws, gcp, vsphere boshio.Stemcells
+
+		BeforeEach(func() {
+			aws = boshio.Stemcells{
+				{Name: "aws", Version: "3"},
+				{Name: "aws", Version: "2"},
+				{Name: "aws", Version: "1"},
+			}
+			gcp = boshio.Stemcells{
+				{Name: "gcp", Version: "3"},
+				{Name: "gcp", Version: "1"},
+			}
+			vsphere = boshio.Stemcells{
+				{Name: "vsphere", Version: "2"},
+				{Name: "vsphere", Version: "1"},
+			}
+		})
+
+		It("returns the stemcells whose version is available in every list", func() {
+			Expect(aws.CommonVersions(gcp, vsphere)).To(Equal(boshio.Stemcells{
+				{Name: "aws", Version: "1"},
+			}))
+		})
+
+		It("returns all stemcells when there are no other lists", func() {
+			Expect(aws.CommonVersions()).To(Equal(aws))
+		})
+
+		Context("when no version is common to every list", func() {
+			It("returns an empty list", func() {
+				Expect(gcp.CommonVersions(vsphere[:1])).To(BeEmpty())
+			})
+		})
+	})
 })
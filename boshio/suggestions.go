@@ -0,0 +1,127 @@
+package boshio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxSuggestions = 3
+
+// UnknownNameError reports a stemcell name that bosh.io does not publish,
+// along with the published names closest to it.
+type UnknownNameError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e UnknownNameError) Error() string {
+	message := fmt.Sprintf("stemcell '%s' is not published on bosh.io", e.Name)
+	if len(e.Suggestions) == 0 {
+		return message
+	}
+
+	quoted := make([]string, len(e.Suggestions))
+	for i, suggestion := range e.Suggestions {
+		quoted[i] = "'" + suggestion + "'"
+	}
+	return fmt.Sprintf("%s, did you mean %s?", message, strings.Join(quoted, " or "))
+}
+
+// CheckName returns an UnknownNameError when the name is not one of the
+// published names.
+func CheckName(name string, names []string) error {
+	for _, published := range names {
+		if published == name {
+			return nil
+		}
+	}
+
+	return UnknownNameError{Name: name, Suggestions: SuggestNames(name, names)}
+}
+
+// SuggestNames returns the names closest to the given name. Names are compared
+// component by component (IaaS, hypervisor, OS, agent), so that a typo in one
+// component weighs less than a missing or extra component.
+func SuggestNames(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	components := nameComponents(name)
+	threshold := len(name) / 5
+
+	var candidates []candidate
+	for _, published := range names {
+		distance := componentDistance(components, nameComponents(published))
+		if distance <= threshold {
+			candidates = append(candidates, candidate{published, distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	suggestions := []string{}
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+func nameComponents(name string) []string {
+	return strings.Split(name, "-")
+}
+
+// componentDistance is the edit distance between two lists of components,
+// where substituting a component costs the edit distance between both and
+// inserting or deleting one costs its length.
+func componentDistance(a, b []string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := 1; j <= len(b); j++ {
+		previous[j] = previous[j-1] + len(b[j-1])
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = previous[0] + len(a[i-1])
+		for j := 1; j <= len(b); j++ {
+			current[j] = min(
+				previous[j]+len(a[i-1]),
+				current[j-1]+len(b[j-1]),
+				previous[j-1]+editDistance(a[i-1], b[j-1]),
+			)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,61 @@
+package boshio_test
+
+import (
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Suggestions", func() {
+	var names []string
+
+	BeforeEach(func() {
+		names = []string{
+			"bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+			"bosh-aws-xen-hvm-ubuntu-jammy-fips-go_agent",
+			"bosh-azure-hyperv-ubuntu-jammy-go_agent",
+			"bosh-google-kvm-ubuntu-jammy-go_agent",
+			"bosh-vsphere-esxi-ubuntu-jammy-go_agent",
+			"bosh-warden-boshlite-ubuntu-jammy-go_agent",
+		}
+	})
+
+	Describe("SuggestNames", func() {
+		It("suggests the names with a misspelled component, closest first", func() {
+			Expect(boshio.SuggestNames("bosh-aws-xen-hvm-ubuntu-jamy-go_agent", names)).To(Equal([]string{
+				"bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+				"bosh-aws-xen-hvm-ubuntu-jammy-fips-go_agent",
+			}))
+		})
+
+		It("suggests the name with a missing component", func() {
+			Expect(boshio.SuggestNames("bosh-aws-xen-ubuntu-jammy-go_agent", names)[0]).To(Equal("bosh-aws-xen-hvm-ubuntu-jammy-go_agent"))
+		})
+
+		It("suggests the names closest to a wrong IaaS first", func() {
+			Expect(boshio.SuggestNames("bosh-gogle-kvm-ubuntu-jammy-go_agent", names)[0]).To(Equal("bosh-google-kvm-ubuntu-jammy-go_agent"))
+		})
+
+		It("does not suggest unrelated names", func() {
+			Expect(boshio.SuggestNames("some-other-thing", names)).To(BeEmpty())
+		})
+	})
+
+	Describe("CheckName", func() {
+		It("accepts a published name", func() {
+			Expect(boshio.CheckName("bosh-google-kvm-ubuntu-jammy-go_agent", names)).To(Succeed())
+		})
+
+		It("reports suggestions for an unknown name", func() {
+			err := boshio.CheckName("bosh-aws-xen-hvm-ubuntu-jamy-go_agent", names)
+			Expect(err).To(MatchError("stemcell 'bosh-aws-xen-hvm-ubuntu-jamy-go_agent' is not published on bosh.io, did you mean 'bosh-aws-xen-hvm-ubuntu-jammy-go_agent' or 'bosh-aws-xen-hvm-ubuntu-jammy-fips-go_agent'?"))
+			Expect(err).To(BeAssignableToTypeOf(boshio.UnknownNameError{}))
+		})
+
+		It("reports an unknown name without suggestions", func() {
+			err := boshio.CheckName("some-other-thing", names)
+			Expect(err).To(MatchError("stemcell 'some-other-thing' is not published on bosh.io"))
+		})
+	})
+})
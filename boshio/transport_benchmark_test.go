@@ -0,0 +1,97 @@
+package boshio_test
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/content"
+	"github.com/concourse/bosh-io-stemcell-resource/fakes"
+)
+
+// rangeServer is a local TLS server serving a tarball with range support,
+// counting the connections opened to it.
+type rangeServer struct {
+	*httptest.Server
+	connections int64
+}
+
+func newRangeServer(tarball []byte, http2 bool) *rangeServer {
+	r := &rangeServer{}
+
+	r.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.ServeContent(w, req, "stemcell.tgz", time.Time{}, bytes.NewReader(tarball))
+	}))
+	r.Server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&r.connections, 1)
+		}
+	}
+	r.Server.EnableHTTP2 = http2
+	r.Server.StartTLS()
+
+	return r
+}
+
+func (r *rangeServer) Connections() int64 {
+	return atomic.LoadInt64(&r.connections)
+}
+
+// BenchmarkDownloadStemcell compares the connections opened and the throughput
+// of downloads with and without reusing connections.
+func BenchmarkDownloadStemcell(b *testing.B) {
+	tarball := bytes.Repeat([]byte("stemcell"), 4*1024*1024)
+
+	for _, bm := range []struct {
+		name   string
+		config boshio.TransportConfig
+	}{
+		{name: "disable_keep_alives", config: boshio.TransportConfig{DisableKeepAlives: true}},
+		{name: "keep_alive", config: boshio.TransportConfig{}},
+		{name: "http2", config: boshio.TransportConfig{HTTP2: true}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			server := newRangeServer(tarball, bm.config.HTTP2)
+			defer server.Close()
+
+			bm.config.CACert = certificatePEMBytes(server.Certificate().Raw)
+			bm.config.Concurrency = 10
+
+			httpClient, err := boshio.NewHTTPClientWithConfig(server.URL, time.Millisecond, bm.config)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			// as many ranges as concurrent requests, like the in script
+			client := boshio.NewClient(httpClient, &fakes.Bar{}, content.NewRanger(10), false)
+			stemcell := boshio.Stemcell{
+				Name:    "some-stemcell",
+				Version: "1.0",
+				Regular: &boshio.Metadata{URL: server.URL + "/stemcell.tgz", SHA1: sha1Hex(tarball)},
+			}
+
+			location := b.TempDir()
+
+			b.SetBytes(int64(len(tarball)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, err = client.DownloadStemcell(stemcell, location, false, boshio.Auth{})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.StopTimer()
+			b.ReportMetric(float64(server.Connections())/float64(b.N), "conns/op")
+
+			os.RemoveAll(location)
+		})
+	}
+}
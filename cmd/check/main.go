@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/concourse/bosh-io-stemcell-resource/boshio"
@@ -13,13 +14,21 @@ import (
 
 type concourseCheck struct {
 	Source struct {
-		Name          string `json:"name"`
-		ForceRegular  bool   `json:"force_regular"`
-		VersionFamily string `json:"version_family"`
-	}
-	Version struct {
-		Version string `json:"version"`
+		Name           string   `json:"name"`
+		Names          []string `json:"names"`
+		ForceRegular   bool     `json:"force_regular"`
+		VersionFamily  string   `json:"version_family"`
+		InitialHistory int      `json:"initial_history"`
+		TrackChecksum  bool     `json:"track_checksum"`
+		VersionFields  []string `json:"version_fields"`
+		RequireFlavors []string `json:"require_flavors"`
+		DisableCache   bool     `json:"disable_cache"`
+		IaaS           string   `json:"iaas"`
+		OS             string   `json:"os"`
+		boshio.TransportConfig
 	}
+	// the version is kept whole, as its extra fields are part of its identity
+	Version map[string]string `json:"version"`
 }
 
 func main() {
@@ -29,26 +38,76 @@ func main() {
 		log.Fatalf("failed reading json: %s", err)
 	}
 
-	httpClient := boshio.NewHTTPClient("https://bosh.io", 5*time.Minute)
+	httpClient, err := boshio.NewHTTPClientWithConfig("https://bosh.io", 5*time.Minute, checkRequest.Source.TransportConfig)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fields := checkRequest.Source.VersionFields
+	if checkRequest.Source.TrackChecksum {
+		fields = append(fields, versions.ChecksumField)
+	}
 
 	client := boshio.NewClient(httpClient, nil, nil, checkRequest.Source.ForceRegular)
-	stemcells, err := client.GetStemcells(checkRequest.Source.Name)
+
+	// check containers are reused between checks, so the metadata is only
+	// downloaded again once bosh.io reports it has changed
+	if !checkRequest.Source.DisableCache {
+		client.Cache = boshio.NewMetadataCache(filepath.Join(os.TempDir(), "bosh-io-stemcell-resource"))
+	}
+
+	if checkRequest.Source.IaaS != "" || checkRequest.Source.OS != "" {
+		if checkRequest.Source.Name != "" || len(checkRequest.Source.Names) > 0 {
+			log.Fatalln("iaas and os cannot be combined with name or names")
+		}
+
+		checkRequest.Source.Name, err = client.FindStemcellName(checkRequest.Source.IaaS, checkRequest.Source.OS)
+		if err != nil {
+			log.Fatalf("failed finding stemcell name: %s", err)
+		}
+	}
+
+	names := checkRequest.Source.Names
+	if len(names) == 0 {
+		names = []string{checkRequest.Source.Name}
+	} else if len(fields) > 0 {
+		// each name publishes its own flavors and checksums, which a single
+		// version cannot describe
+		log.Fatalln("version_fields and track_checksum are not supported when tracking several stemcell names")
+	}
+
+	options := versions.FilterOptions{
+		InitialVersion: checkRequest.Version["version"],
+		InitialFields:  checkRequest.Version,
+		VersionFamily:  checkRequest.Source.VersionFamily,
+		InitialHistory: checkRequest.Source.InitialHistory,
+		Fields:         fields,
+	}
+
+	stemcells, invalid, err := versions.FetchStemcells(client, names, checkRequest.Source.RequireFlavors, options)
 	if err != nil {
-		log.Fatalf("failed getting stemcell: %s", err)
+		log.Fatalln(err)
 	}
 
-	stemcells = stemcells.FilterByType()
-	filter := versions.NewFilter(
-		checkRequest.Version.Version,
-		stemcells,
-		checkRequest.Source.VersionFamily,
-	)
+	filter := versions.NewFilter(stemcells, options)
 
 	filteredVersions, err := filter.Versions()
 	if err != nil {
 		log.Fatalf("failed filtering versions: %s", err)
 	}
 
+	// invalid entries are skipped, unless they are a version the check would
+	// emit, which would otherwise be silently passed over
+	for _, skipped := range invalid {
+		selected, err := filter.Selects(skipped.Version)
+		if err != nil {
+			log.Fatalf("failed filtering versions: %s", err)
+		}
+		if selected {
+			log.Fatalf("failed checking stemcell version '%s': %s", skipped.Version, skipped)
+		}
+	}
+
 	content, err := json.Marshal(filteredVersions)
 	if err != nil {
 		log.Fatalf("failed to marshal: %s", err)
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestIn(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "In Suite")
+}
+
+// fakeBoshio serves the metadata of every stemcell name it is asked for, with
+// a light and a regular flavor of version 1.1, and a version 1.0 missing its
+// url. vSphere names are published without a light flavor. When a Tarball is
+// set, it is served for both flavors along with its real checksums.
+type fakeBoshio struct {
+	Tarball []byte
+
+	s *httptest.Server
+}
+
+func newFakeBoshio() *fakeBoshio {
+	f := &fakeBoshio{}
+	f.s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, ".tgz") {
+			http.ServeContent(w, req, "stemcell.tgz", time.Time{}, bytes.NewReader(f.Tarball))
+			return
+		}
+
+		name := strings.TrimPrefix(req.URL.Path, "/api/v1/stemcells/")
+
+		lightChecksums := `"size": 100, "md5": "light-md5", "sha1": "light-sha1", "sha256": "light-sha256"`
+		regularChecksums := `"size": 2000, "md5": "regular-md5", "sha1": "regular-sha1", "sha256": "regular-sha256"`
+		if f.Tarball != nil {
+			lightChecksums = fmt.Sprintf(`"size": %d, "md5": "%x", "sha1": "%x", "sha256": "%x"`,
+				len(f.Tarball), md5.Sum(f.Tarball), sha1.Sum(f.Tarball), sha256.Sum256(f.Tarball))
+			regularChecksums = lightChecksums
+		}
+
+		light := fmt.Sprintf(`"light": {"url": "%[2]s/light-%[1]s.tgz", %[3]s},`, name, f.s.URL, lightChecksums)
+		if strings.Contains(name, "vsphere") {
+			light = ""
+		}
+
+		fmt.Fprintf(w, `[{
+			"name": "%[1]s",
+			"version": "1.1",
+			%[3]s
+			"regular": {"url": "%[2]s/%[1]s.tgz", %[4]s}
+		}, {
+			"name": "%[1]s",
+			"version": "1.0",
+			"light": {"size": 100, "sha1": "light-sha1"}
+		}]`, name, f.s.URL, light, regularChecksums)
+	}))
+	return f
+}
+
+func (f *fakeBoshio) URL() string {
+	return f.s.URL
+}
+
+func (f *fakeBoshio) Close() {
+	f.s.Close()
+}
+
+// gzippedTarball returns a gzipped tarball holding the given files.
+func gzippedTarball(files map[string]string) []byte {
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, contents := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tarWriter.Write([]byte(contents))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Expect(tarWriter.Close()).To(Succeed())
+	Expect(gzipWriter.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
@@ -2,40 +2,64 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/concourse/bosh-io-stemcell-resource/boshio"
 	"github.com/concourse/bosh-io-stemcell-resource/content"
+	"github.com/concourse/bosh-io-stemcell-resource/manifest"
 	"github.com/concourse/bosh-io-stemcell-resource/progress"
+	"github.com/concourse/bosh-io-stemcell-resource/sbom"
+	"github.com/concourse/bosh-io-stemcell-resource/tarball"
+	"github.com/dustin/go-humanize"
 )
 
 const routines = 10
 
 type concourseInRequest struct {
 	Source struct {
-		Name         string `json:"name"`
-		ForceRegular bool   `json:"force_regular"`
-		Auth         struct {
+		Name            string                  `json:"name"`
+		Names           []string                `json:"names"`
+		ForceRegular    bool                    `json:"force_regular"`
+		IaaS            string                  `json:"iaas"`
+		OS              string                  `json:"os"`
+		VerifySignature *boshio.SignatureConfig `json:"verify_signature"`
+		Auth            struct {
 			AccessKey string `json:"access_key"`
 			SecretKey string `json:"secret_key"`
 		} `json:"auth"`
+		boshio.TransportConfig
 	} `json:"source"`
 	Params struct {
-		Tarball          bool `json:"tarball"`
-		PreserveFilename bool `json:"preserve_filename"`
+		Tarball          bool     `json:"tarball"`
+		PreserveFilename bool     `json:"preserve_filename"`
+		Flavors          []string `json:"flavors"`
+		MetadataYML      bool     `json:"metadata_yml"`
+		ManifestSnippets bool     `json:"emit_manifest_snippets"`
+		ExtractManifest  bool     `json:"extract_manifest"`
+		ExtractImage     bool     `json:"extract_image"`
+		SBOM             bool     `json:"sbom"`
+		MaxBandwidth     string   `json:"max_bandwidth"`
 	} `json:"params"`
-	Version struct {
-		Version string `json:"version"`
-	} `json:"version"`
+	Version concourseVersion `json:"version"`
+}
+
+type concourseVersion struct {
+	Version string `json:"version"`
+	Flavor  string `json:"flavor,omitempty"`
+	MD5     string `json:"md5,omitempty"`
+	SHA1    string `json:"sha1,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
 }
 
 type concourseInResponse struct {
-	Version struct {
-		Version string `json:"version"`
-	} `json:"version"`
+	Version  concourseVersion         `json:"version"`
 	Metadata []concourseMetadataField `json:"metadata"`
 }
 
@@ -55,39 +79,197 @@ func main() {
 
 	location := os.Args[1]
 
-	httpClient := boshio.NewHTTPClient("https://bosh.io", 800*time.Millisecond)
+	inRequest.Source.TransportConfig.Concurrency = routines
+
+	httpClient, err := boshio.NewHTTPClientWithConfig("https://bosh.io", 800*time.Millisecond, inRequest.Source.TransportConfig)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	client := boshio.NewClient(httpClient, progress.NewBar(), content.NewRanger(routines), inRequest.Source.ForceRegular)
+	client.Transport = httpClient.Client.Transport
+
+	if inRequest.Params.MaxBandwidth != "" {
+		bytesPerSecond, err := content.ParseBandwidth(inRequest.Params.MaxBandwidth)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		// shared by every download, so that fetching several flavors or names
+		// stays within the bandwidth as well
+		client.Limiter = content.NewLimiter(bytesPerSecond)
+	}
+
+	if inRequest.Source.VerifySignature != nil {
+		client.Verifier, err = boshio.NewSignatureVerifier(*inRequest.Source.VerifySignature)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if inRequest.Source.IaaS != "" || inRequest.Source.OS != "" {
+		if inRequest.Source.Name != "" || len(inRequest.Source.Names) > 0 {
+			log.Fatalln("iaas and os cannot be combined with name or names")
+		}
+
+		inRequest.Source.Name, err = client.FindStemcellName(inRequest.Source.IaaS, inRequest.Source.OS)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	var metadata []concourseMetadataField
+
+	if len(inRequest.Source.Names) == 0 {
+		metadata, err = get(client, inRequest.Source.Name, location, inRequest)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		metadata, err = getNames(client, inRequest.Source.Names, location, inRequest)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	json.NewEncoder(os.Stdout).Encode(concourseInResponse{
+		Version:  inRequest.Version,
+		Metadata: metadata,
+	},
+	)
+}
 
-	stemcells, err := client.GetStemcells(inRequest.Source.Name)
+// getNames fetches the requested version of every named stemcell into a
+// subdirectory of the location named after it.
+func getNames(client *boshio.Client, names []string, location string, inRequest concourseInRequest) ([]concourseMetadataField, error) {
+	err := os.WriteFile(filepath.Join(location, "version"), []byte(inRequest.Version.Version), 0644)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
+	}
+
+	// the flavor and checksums of a version describe a single name, so each
+	// name is fetched in its own default flavor
+	nameRequest := inRequest
+	nameRequest.Version = concourseVersion{Version: inRequest.Version.Version}
+
+	var metadata []concourseMetadataField
+	for _, name := range names {
+		nameLocation := filepath.Join(location, name)
+		err = os.MkdirAll(nameLocation, 0755)
+		if err != nil {
+			return nil, err
+		}
+
+		nameMetadata, err := get(client, name, nameLocation, nameRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range nameMetadata {
+			m.Name = fmt.Sprintf("%s_%s", name, m.Name)
+			metadata = append(metadata, m)
+		}
+	}
+
+	return metadata, nil
+}
+
+// get fetches the requested version of the named stemcell into the location,
+// placing each requested flavor in its own subdirectory.
+func get(client *boshio.Client, name string, location string, inRequest concourseInRequest) ([]concourseMetadataField, error) {
+	stemcells, invalid, err := client.GetStemcells(name)
+	if err != nil {
+		return nil, err
 	}
 
 	stemcell, ok := stemcells.FindStemcellByVersion(inRequest.Version.Version)
 	if !ok {
-		log.Fatalf("failed to find stemcell matching version: '%s'\n", inRequest.Version.Version)
+		if skipped, ok := invalid.FindByVersion(inRequest.Version.Version); ok {
+			return nil, fmt.Errorf("failed to fetch stemcell version '%s': %s", inRequest.Version.Version, skipped)
+		}
+		return nil, fmt.Errorf("failed to find stemcell matching version: '%s'", inRequest.Version.Version)
 	}
 
-	dataLocations := []string{"version", "sha1", "sha256", "url"}
+	// a version emitted with its flavor is fetched in that flavor, which
+	// its checksums describe
+	if inRequest.Version.Flavor != "" {
+		stemcell, err = stemcell.WithFlavor(inRequest.Version.Flavor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = stemcell.VerifyChecksum(inRequest.Version.SHA1, inRequest.Version.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inRequest.Params.Flavors) == 0 {
+		return fetch(client, stemcell, location, inRequest)
+	}
+
+	err = writeMetadataFile(client, stemcell, "version", location)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata []concourseMetadataField
+	for _, flavor := range inRequest.Params.Flavors {
+		flavoredStemcell, err := stemcell.WithFlavor(flavor)
+		if err != nil {
+			return nil, err
+		}
+
+		flavorLocation := filepath.Join(location, flavor)
+		err = os.MkdirAll(flavorLocation, 0755)
+		if err != nil {
+			return nil, err
+		}
+
+		flavorMetadata, err := fetch(client, flavoredStemcell, flavorLocation, inRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range flavorMetadata {
+			m.Name = fmt.Sprintf("%s_%s", flavor, m.Name)
+			metadata = append(metadata, m)
+		}
+	}
+
+	return metadata, nil
+}
+
+// fetch writes the metadata files of the stemcell to the location and
+// downloads the tarball when requested.
+func fetch(client *boshio.Client, stemcell boshio.Stemcell, location string, inRequest concourseInRequest) ([]concourseMetadataField, error) {
+	dataLocations := []string{"version", "sha1", "sha256", "url", "metadata.json"}
+	if inRequest.Params.MetadataYML {
+		dataLocations = append(dataLocations, "metadata.yml")
+	}
 
 	for _, name := range dataLocations {
-		fileLocation, err := os.Create(filepath.Join(location, name))
+		err := writeMetadataFile(client, stemcell, name, location)
 		if err != nil {
-			log.Fatalln(err)
+			return nil, err
 		}
-		defer fileLocation.Close()
+	}
 
-		err = client.WriteMetadata(stemcell, name, fileLocation)
+	if inRequest.Params.ManifestSnippets {
+		err := manifest.WriteSnippets(stemcell, location)
 		if err != nil {
-			log.Fatalln(err)
+			return nil, err
 		}
 	}
 
+	var transfer boshio.Transfer
 	if inRequest.Params.Tarball {
-		err = client.DownloadStemcell(stemcell, location, inRequest.Params.PreserveFilename, boshio.Auth(inRequest.Source.Auth))
+		// a finished progress bar cannot be restarted
+		client.Bar = progress.NewBar()
+
+		var err error
+		transfer, err = client.DownloadStemcell(stemcell, location, inRequest.Params.PreserveFilename, boshio.Auth(inRequest.Source.Auth))
 		if err != nil {
-			log.Fatalln(err)
+			return nil, err
 		}
 	}
 
@@ -101,9 +283,148 @@ func main() {
 		metadata = append(metadata, m)
 	}
 
-	json.NewEncoder(os.Stdout).Encode(concourseInResponse{
-		Version:  inRequest.Version,
-		Metadata: metadata,
-	},
+	metadata = append(metadata,
+		concourseMetadataField{Name: "flavor", Value: stemcell.Flavor()},
+		concourseMetadataField{Name: "size", Value: humanize.IBytes(uint64(stemcell.Details().Size))},
+		concourseMetadataField{Name: "provider", Value: stemcell.Details().Provider()},
 	)
+
+	// names that do not follow the bosh.io convention are simply not described
+	if name, err := boshio.ParseStemcellName(stemcell.Name); err == nil {
+		metadata = append(metadata,
+			concourseMetadataField{Name: "iaas", Value: name.IaaS},
+			concourseMetadataField{Name: "hypervisor", Value: name.Hypervisor},
+			concourseMetadataField{Name: "os", Value: name.OS},
+			concourseMetadataField{Name: "agent", Value: name.Agent},
+		)
+		if name.FIPS {
+			metadata = append(metadata, concourseMetadataField{Name: "fips", Value: "true"})
+		}
+	}
+
+	if transfer.Duration > 0 {
+		metadata = append(metadata,
+			concourseMetadataField{Name: "download_duration", Value: transfer.Duration.Round(time.Millisecond).String()},
+			concourseMetadataField{Name: "download_throughput", Value: humanize.IBytes(uint64(transfer.Throughput())) + "/s"},
+		)
+	}
+
+	if inRequest.Params.ExtractManifest || inRequest.Params.SBOM || inRequest.Params.ExtractImage {
+		manifestMetadata, err := inspectTarball(stemcell, location, inRequest)
+		if err != nil {
+			return nil, err
+		}
+		// stemcell.MF is authoritative over the fields parsed from the name
+		for _, m := range manifestMetadata {
+			metadata = setMetadataField(metadata, m)
+		}
+	}
+
+	return metadata, nil
+}
+
+// setMetadataField replaces the value of the field with the same name, or
+// appends the field when there is none.
+func setMetadataField(metadata []concourseMetadataField, field concourseMetadataField) []concourseMetadataField {
+	for i, m := range metadata {
+		if m.Name == field.Name {
+			metadata[i] = field
+			return metadata
+		}
+	}
+	return append(metadata, field)
+}
+
+// inspectTarball extracts the manifests from the downloaded tarball, validates
+// them against the stemcell and generates the SBOMs and unpacks the image when
+// requested.
+func inspectTarball(stemcell boshio.Stemcell, location string, inRequest concourseInRequest) ([]concourseMetadataField, error) {
+	if !inRequest.Params.Tarball {
+		return nil, errors.New("extract_manifest, extract_image and sbom require the tarball to be fetched")
+	}
+
+	tarballFileName, err := boshio.TarballFileName(stemcell, inRequest.Params.PreserveFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	tarballPath := filepath.Join(location, tarballFileName)
+
+	stemcellManifest, err := tarball.ExtractManifest(tarballPath, location)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stemcellManifest.Validate(stemcell)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := []concourseMetadataField{
+		{Name: "os", Value: stemcellManifest.OperatingSystem},
+	}
+	if stemcellManifest.AgentVersion != "" {
+		metadata = append(metadata, concourseMetadataField{Name: "agent_version", Value: stemcellManifest.AgentVersion})
+	}
+
+	if inRequest.Params.SBOM {
+		err = writeSBOMs(stemcell, location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if inRequest.Params.ExtractImage {
+		imageFiles, err := tarball.ExtractImage(tarballPath, location, stemcellManifest)
+		if err != nil {
+			return nil, err
+		}
+		metadata = append(metadata, concourseMetadataField{Name: "image", Value: strings.Join(imageFiles, ", ")})
+	}
+
+	return metadata, nil
+}
+
+func writeSBOMs(stemcell boshio.Stemcell, location string) error {
+	dpkgList, err := os.Open(filepath.Join(location, tarball.DpkgFile))
+	if err != nil {
+		return fmt.Errorf("failed to generate sbom: %s", err)
+	}
+	defer dpkgList.Close()
+
+	packages, err := sbom.ParseDpkgList(dpkgList)
+	if err != nil {
+		return err
+	}
+
+	created := time.Now()
+	documents := map[string]func(io.Writer, boshio.Stemcell, []sbom.Package, time.Time) error{
+		sbom.SPDXFile:      sbom.WriteSPDX,
+		sbom.CycloneDXFile: sbom.WriteCycloneDX,
+	}
+
+	for fileName, write := range documents {
+		f, err := os.Create(filepath.Join(location, fileName))
+		if err != nil {
+			return err
+		}
+
+		err = write(f, stemcell, packages, created)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeMetadataFile(client *boshio.Client, stemcell boshio.Stemcell, name string, location string) error {
+	fileLocation, err := os.Create(filepath.Join(location, name))
+	if err != nil {
+		return err
+	}
+	defer fileLocation.Close()
+
+	return client.WriteMetadata(stemcell, name, fileLocation)
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/content"
+	"github.com/concourse/bosh-io-stemcell-resource/fakes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("in", func() {
+	var (
+		server    *fakeBoshio
+		client    *boshio.Client
+		location  string
+		inRequest concourseInRequest
+	)
+
+	readFile := func(path ...string) string {
+		contents, err := os.ReadFile(filepath.Join(append([]string{location}, path...)...))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	BeforeEach(func() {
+		server = newFakeBoshio()
+		client = boshio.NewClient(boshio.NewHTTPClient(server.URL(), time.Millisecond), nil, nil, false)
+
+		var err error
+		location, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		inRequest = concourseInRequest{}
+		inRequest.Version.Version = "1.1"
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(location)
+	})
+
+	Describe("get", func() {
+		It("writes the metadata of the default flavor into the location", func() {
+			metadata, err := get(client, "some-stemcell", location, inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readFile("version")).To(Equal("1.1"))
+			Expect(readFile("sha1")).To(Equal("light-sha1"))
+			Expect(readFile("url")).To(Equal(server.URL() + "/light-some-stemcell.tgz"))
+			Expect(metadata).To(ContainElement(concourseMetadataField{Name: "flavor", Value: "light"}))
+		})
+
+		It("describes the stemcell with the fields parsed from its name", func() {
+			metadata, err := get(client, "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", location, inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(metadata).To(ContainElements(
+				concourseMetadataField{Name: "iaas", Value: "aws"},
+				concourseMetadataField{Name: "hypervisor", Value: "xen-hvm"},
+				concourseMetadataField{Name: "os", Value: "ubuntu-jammy"},
+				concourseMetadataField{Name: "agent", Value: "go_agent"},
+			))
+		})
+
+		Context("when the manifest is extracted", func() {
+			BeforeEach(func() {
+				server.Tarball = gzippedTarball(map[string]string{
+					"stemcell.MF": "name: bosh-aws-xen-hvm-ubuntu-jammy-go_agent\nversion: '1.1'\noperating_system: some-os\n",
+				})
+				client = boshio.NewClient(boshio.NewHTTPClient(server.URL(), time.Millisecond), &fakes.Bar{}, content.NewRanger(1), false)
+
+				inRequest.Params.Tarball = true
+				inRequest.Params.ExtractManifest = true
+			})
+
+			It("takes the os from stemcell.MF instead of the name", func() {
+				metadata, err := get(client, "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", location, inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				var osFields []concourseMetadataField
+				for _, m := range metadata {
+					if m.Name == "os" {
+						osFields = append(osFields, m)
+					}
+				}
+				Expect(osFields).To(Equal([]concourseMetadataField{{Name: "os", Value: "some-os"}}))
+			})
+		})
+
+		Context("when the version was emitted with its flavor", func() {
+			It("fetches the flavor of the version", func() {
+				inRequest.Version.Flavor = "regular"
+				inRequest.Version.SHA256 = "regular-sha256"
+
+				metadata, err := get(client, "some-stemcell", location, inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("sha1")).To(Equal("regular-sha1"))
+				Expect(metadata).To(ContainElement(concourseMetadataField{Name: "flavor", Value: "regular"}))
+			})
+		})
+
+		Context("when several flavors are requested", func() {
+			BeforeEach(func() {
+				inRequest.Params.Flavors = []string{"light", "regular"}
+			})
+
+			It("writes each flavor into its own subdirectory", func() {
+				metadata, err := get(client, "some-stemcell", location, inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("version")).To(Equal("1.1"))
+				for _, flavor := range []string{"light", "regular"} {
+					Expect(readFile(flavor, "version")).To(Equal("1.1"))
+					Expect(readFile(flavor, "sha1")).To(Equal(flavor + "-sha1"))
+					Expect(readFile(flavor, "sha256")).To(Equal(flavor + "-sha256"))
+					Expect(filepath.Join(location, flavor, "url")).To(BeARegularFile())
+					Expect(filepath.Join(location, flavor, "metadata.json")).To(BeARegularFile())
+				}
+
+				Expect(filepath.Join(location, "sha1")).NotTo(BeAnExistingFile())
+				Expect(metadata).To(ContainElements(
+					concourseMetadataField{Name: "light_sha1", Value: "light-sha1"},
+					concourseMetadataField{Name: "regular_sha1", Value: "regular-sha1"},
+				))
+			})
+
+			It("returns an error for an unknown flavor", func() {
+				inRequest.Params.Flavors = []string{"heavy"}
+
+				_, err := get(client, "some-stemcell", location, inRequest)
+				Expect(err).To(MatchError("unknown stemcell flavor 'heavy': must be one of 'light' or 'regular'"))
+			})
+		})
+
+		Context("when the version is published with invalid metadata", func() {
+			It("returns the reason it cannot be fetched", func() {
+				inRequest.Version.Version = "1.0"
+
+				_, err := get(client, "some-stemcell", location, inRequest)
+				Expect(err).To(MatchError("failed to fetch stemcell version '1.0': malformed stemcell at index 1 (version '1.0'): light flavor is missing its url"))
+			})
+		})
+
+		Context("when the version does not exist", func() {
+			It("returns an error", func() {
+				inRequest.Version.Version = "2.2"
+
+				_, err := get(client, "some-stemcell", location, inRequest)
+				Expect(err).To(MatchError("failed to find stemcell matching version: '2.2'"))
+			})
+		})
+	})
+
+	Describe("getNames", func() {
+		names := []string{"bosh-aws-xen-hvm-ubuntu-jammy-go_agent", "bosh-google-kvm-ubuntu-jammy-go_agent"}
+
+		It("writes each name into its own subdirectory", func() {
+			metadata, err := getNames(client, names, location, inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readFile("version")).To(Equal("1.1"))
+			for _, name := range names {
+				Expect(readFile(name, "version")).To(Equal("1.1"))
+				Expect(readFile(name, "url")).To(Equal(server.URL() + "/light-" + name + ".tgz"))
+				Expect(filepath.Join(location, name, "metadata.json")).To(BeARegularFile())
+			}
+
+			Expect(metadata).To(ContainElements(
+				concourseMetadataField{Name: names[0] + "_iaas", Value: "aws"},
+				concourseMetadataField{Name: names[1] + "_iaas", Value: "google"},
+			))
+		})
+
+		Context("when a name has no light flavor", func() {
+			It("fetches each name in its own default flavor", func() {
+				names := []string{"bosh-aws-xen-hvm-ubuntu-jammy-go_agent", "bosh-vsphere-esxi-ubuntu-jammy-go_agent"}
+				inRequest.Version.Flavor = "light"
+				inRequest.Version.SHA256 = "light-sha256"
+
+				metadata, err := getNames(client, names, location, inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile(names[0], "url")).To(Equal(server.URL() + "/light-" + names[0] + ".tgz"))
+				Expect(readFile(names[1], "url")).To(Equal(server.URL() + "/" + names[1] + ".tgz"))
+				Expect(metadata).To(ContainElements(
+					concourseMetadataField{Name: names[0] + "_flavor", Value: "light"},
+					concourseMetadataField{Name: names[1] + "_flavor", Value: "regular"},
+				))
+			})
+		})
+
+		Context("when several flavors are requested", func() {
+			It("writes each flavor into a subdirectory of each name", func() {
+				inRequest.Params.Flavors = []string{"light", "regular"}
+
+				metadata, err := getNames(client, names, location, inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("version")).To(Equal("1.1"))
+				for _, name := range names {
+					Expect(readFile(name, "version")).To(Equal("1.1"))
+					Expect(readFile(name, "light", "version")).To(Equal("1.1"))
+					Expect(readFile(name, "regular", "version")).To(Equal("1.1"))
+					Expect(readFile(name, "regular", "url")).To(Equal(server.URL() + "/" + name + ".tgz"))
+				}
+
+				Expect(metadata).To(ContainElement(concourseMetadataField{Name: names[1] + "_regular_sha1", Value: "regular-sha1"}))
+			})
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOut(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Out Suite")
+}
+
+// fakeDirector is a minimal stand-in for a BOSH director with basic auth,
+// whose upload tasks finish immediately.
+type fakeDirector struct {
+	Stemcells []map[string]string
+	Uploads   []string
+
+	mutex sync.Mutex
+	s     *httptest.Server
+}
+
+func newFakeDirector() *fakeDirector {
+	d := &fakeDirector{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"user_authentication":{"type":"basic"}}`))
+	})
+	mux.HandleFunc("/stemcells", d.stemcells)
+	mux.HandleFunc("/tasks/1", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"id":1,"state":"done"}`))
+	})
+	d.s = httptest.NewServer(mux)
+
+	return d
+}
+
+func (d *fakeDirector) URL() string {
+	return d.s.URL
+}
+
+func (d *fakeDirector) Close() {
+	d.s.Close()
+}
+
+func (d *fakeDirector) stemcells(w http.ResponseWriter, req *http.Request) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if req.Method == "GET" {
+		json.NewEncoder(w).Encode(d.Stemcells)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	Expect(err).NotTo(HaveOccurred())
+	d.Uploads = append(d.Uploads, string(body))
+
+	w.Header().Set("Location", fmt.Sprintf("%s/tasks/1", d.s.URL))
+	w.WriteHeader(http.StatusFound)
+}
@@ -1,7 +1,281 @@
 package main
 
-import "log"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/director"
+	"github.com/concourse/bosh-io-stemcell-resource/mirror"
+)
+
+type concourseOutRequest struct {
+	Source struct {
+		Name     string          `json:"name"`
+		IaaS     string          `json:"iaas"`
+		OS       string          `json:"os"`
+		Director director.Config `json:"director"`
+		Mirror   mirror.Config   `json:"mirror"`
+		Auth     struct {
+			AccessKey string `json:"access_key"`
+			SecretKey string `json:"secret_key"`
+		} `json:"auth"`
+		boshio.TransportConfig
+	} `json:"source"`
+	Params struct {
+		Stemcell string `json:"stemcell"`
+	} `json:"params"`
+}
+
+type concourseOutResponse struct {
+	Version struct {
+		Version string `json:"version"`
+	} `json:"version"`
+	Metadata []concourseMetadataField `json:"metadata"`
+}
+
+type concourseMetadataField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
 
 func main() {
-	log.Fatalln("put step is not supported for this resource")
+	var outRequest concourseOutRequest
+	err := json.NewDecoder(os.Stdin).Decode(&outRequest)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	httpClient, err := boshio.NewHTTPClientWithConfig("https://bosh.io", 5*time.Minute, outRequest.Source.TransportConfig)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// bosh.io is only asked for the name of a stemcell selected by iaas and os
+	client := boshio.NewClient(httpClient, nil, nil, false)
+
+	response, err := put(client, outRequest, os.Args[1])
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(response)
+}
+
+// put uploads the stemcell fetched into the stemcell param to the director
+// and/or mirror.
+func put(client *boshio.Client, outRequest concourseOutRequest, sourcesDir string) (concourseOutResponse, error) {
+	var response concourseOutResponse
+
+	if outRequest.Source.Director.URL == "" && outRequest.Source.Mirror.URL == "" {
+		return response, errors.New("put step is not supported for this resource without a director or mirror")
+	}
+
+	if (outRequest.Source.IaaS != "" || outRequest.Source.OS != "") && outRequest.Source.Name != "" {
+		return response, errors.New("iaas and os cannot be combined with name")
+	}
+
+	if outRequest.Params.Stemcell == "" {
+		return response, errors.New("params.stemcell must be set to the directory of a fetched stemcell")
+	}
+
+	stemcellDir := filepath.Join(sourcesDir, outRequest.Params.Stemcell)
+
+	version, err := readFile(stemcellDir, "version")
+	if err != nil {
+		return response, err
+	}
+
+	name, err := stemcellName(client, outRequest, stemcellDir)
+	if err != nil {
+		return response, err
+	}
+
+	response.Version.Version = version
+	response.Metadata = []concourseMetadataField{
+		{Name: "name", Value: name},
+	}
+
+	if outRequest.Source.Mirror.URL != "" {
+		metadata, err := mirrorStemcell(outRequest, stemcellDir, name, version)
+		if err != nil {
+			return response, err
+		}
+		response.Metadata = append(response.Metadata, metadata...)
+	}
+
+	if outRequest.Source.Director.URL != "" {
+		metadata, err := uploadStemcell(outRequest, stemcellDir, name, version)
+		if err != nil {
+			return response, err
+		}
+		response.Metadata = append(response.Metadata, metadata...)
+	}
+
+	return response, nil
+}
+
+// stemcellName reads the name of the fetched stemcell from its metadata.json,
+// which get writes however the source selects the stemcell, and falls back to
+// the name of the source, or the name bosh.io publishes for its iaas and os as
+// check and get resolve it.
+func stemcellName(client *boshio.Client, outRequest concourseOutRequest, stemcellDir string) (string, error) {
+	contents, err := os.ReadFile(filepath.Join(stemcellDir, "metadata.json"))
+	if err == nil {
+		var document boshio.MetadataDocument
+		err = json.Unmarshal(contents, &document)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse metadata.json: %s", err)
+		}
+		if document.Name != "" {
+			return document.Name, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if outRequest.Source.Name != "" {
+		return outRequest.Source.Name, nil
+	}
+
+	if outRequest.Source.IaaS != "" || outRequest.Source.OS != "" {
+		name, err := client.FindStemcellName(outRequest.Source.IaaS, outRequest.Source.OS)
+		if err != nil {
+			return "", fmt.Errorf("failed to determine the stemcell name: %s", err)
+		}
+		return name, nil
+	}
+
+	return "", errors.New("failed to determine the stemcell name: the fetched stemcell has no metadata.json and neither source.name nor source.iaas and source.os are set")
+}
+
+// mirrorStemcell copies the fetched tarball into the mirror bucket.
+func mirrorStemcell(outRequest concourseOutRequest, stemcellDir string, name string, version string) ([]concourseMetadataField, error) {
+	m, err := mirror.New(outRequest.Source.Mirror, boshio.Auth(outRequest.Source.Auth), outRequest.Source.TransportConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	tarball, err := findTarball(stemcellDir)
+	if err != nil {
+		return nil, err
+	}
+	if tarball == "" {
+		return nil, errors.New("mirroring requires the stemcell tarball, fetch it with the tarball param")
+	}
+
+	// the checksums written by get are verified against the actual bits
+	var expected boshio.Metadata
+	expected.SHA1, _ = readFile(stemcellDir, "sha1")
+	expected.SHA256, _ = readFile(stemcellDir, "sha256")
+
+	result, err := m.Upload(name, version, tarball, expected)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Skipped {
+		fmt.Fprintf(os.Stderr, "Stemcell %s/%s is already mirrored, skipping upload\n", name, version)
+	}
+
+	return []concourseMetadataField{
+		{Name: "mirror_url", Value: result.Metadata.URL},
+		{Name: "mirrored", Value: strconv.FormatBool(!result.Skipped)},
+	}, nil
+}
+
+// uploadStemcell uploads the fetched stemcell to the director unless it
+// already has it.
+func uploadStemcell(outRequest concourseOutRequest, stemcellDir string, name string, version string) ([]concourseMetadataField, error) {
+	client, err := director.NewClient(outRequest.Source.Director, outRequest.Source.TransportConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.HasStemcell(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		fmt.Fprintf(os.Stderr, "Stemcell %s/%s already exists on the director, skipping upload\n", name, version)
+	} else {
+		err = upload(client, stemcellDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []concourseMetadataField{
+		{Name: "director", Value: outRequest.Source.Director.URL},
+		{Name: "uploaded", Value: strconv.FormatBool(!exists)},
+	}, nil
+}
+
+// upload sends the tarball in the stemcell directory to the director, falling
+// back to letting the director fetch the url when no tarball was downloaded.
+func upload(client *director.Client, stemcellDir string) error {
+	tarballPath, err := findTarball(stemcellDir)
+	if err != nil {
+		return err
+	}
+
+	if tarballPath != "" {
+		tarball, err := os.Open(tarballPath)
+		if err != nil {
+			return err
+		}
+		defer tarball.Close()
+
+		info, err := tarball.Stat()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Uploading %s to the director\n", filepath.Base(tarballPath))
+		return client.UploadStemcell(tarball, info.Size())
+	}
+
+	stemcellURL, err := readFile(stemcellDir, "url")
+	if err != nil {
+		return err
+	}
+
+	sha1, err := readFile(stemcellDir, "sha1")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Uploading %s to the director\n", stemcellURL)
+	return client.UploadStemcellURL(stemcellURL, sha1)
+}
+
+// findTarball returns the path of the tarball in the stemcell directory, which
+// may have its original filename, or an empty string if there is none.
+func findTarball(stemcellDir string) (string, error) {
+	tarballs, err := filepath.Glob(filepath.Join(stemcellDir, "*.tgz"))
+	if err != nil || len(tarballs) == 0 {
+		return "", err
+	}
+
+	if len(tarballs) > 1 {
+		return "", fmt.Errorf("found several tarballs in %s: %s, expected the one fetched by get", stemcellDir, strings.Join(tarballs, ", "))
+	}
+
+	return tarballs[0], nil
+}
+
+func readFile(dir string, name string) (string, error) {
+	contents, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(contents)), nil
 }
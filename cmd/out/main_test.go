@@ -0,0 +1,196 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("out", func() {
+	var (
+		director   *fakeDirector
+		boshioAPI  *httptest.Server
+		client     *boshio.Client
+		sourcesDir string
+		outRequest concourseOutRequest
+	)
+
+	writeFile := func(name string, contents string) {
+		path := filepath.Join(sourcesDir, "stemcell", name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		director = newFakeDirector()
+
+		boshioAPI = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`[{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent"}, {"name": "bosh-google-kvm-ubuntu-jammy-go_agent"}]`))
+		}))
+		client = boshio.NewClient(boshio.NewHTTPClient(boshioAPI.URL, time.Millisecond), nil, nil, false)
+
+		var err error
+		sourcesDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		writeFile("version", "1.1")
+		writeFile("url", "https://example.com/stemcell.tgz")
+		writeFile("sha1", "some-sha1")
+		writeFile("metadata.json", `{"name":"bosh-google-kvm-ubuntu-jammy-go_agent","version":"1.1"}`)
+
+		outRequest = concourseOutRequest{}
+		outRequest.Source.Director.URL = director.URL()
+		outRequest.Source.Director.Client = "some-client"
+		outRequest.Source.Director.ClientSecret = "some-secret"
+		outRequest.Params.Stemcell = "stemcell"
+	})
+
+	AfterEach(func() {
+		director.Close()
+		boshioAPI.Close()
+		os.RemoveAll(sourcesDir)
+	})
+
+	Describe("put", func() {
+		It("uploads the fetched tarball and emits its version", func() {
+			writeFile("stemcell.tgz", "some-tarball")
+
+			response, err := put(client, outRequest, sourcesDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Version.Version).To(Equal("1.1"))
+			Expect(response.Metadata).To(ContainElements(
+				concourseMetadataField{Name: "name", Value: "bosh-google-kvm-ubuntu-jammy-go_agent"},
+				concourseMetadataField{Name: "uploaded", Value: "true"},
+			))
+			Expect(director.Uploads).To(Equal([]string{"some-tarball"}))
+		})
+
+		It("asks the director to fetch the url without a tarball", func() {
+			_, err := put(client, outRequest, sourcesDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(director.Uploads).To(HaveLen(1))
+			Expect(director.Uploads[0]).To(MatchJSON(`{"location":"https://example.com/stemcell.tgz","sha1":"some-sha1"}`))
+		})
+
+		It("skips the upload when the director has the stemcell named in metadata.json", func() {
+			director.Stemcells = []map[string]string{{"name": "bosh-google-kvm-ubuntu-jammy-go_agent", "version": "1.1"}}
+
+			response, err := put(client, outRequest, sourcesDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Metadata).To(ContainElement(concourseMetadataField{Name: "uploaded", Value: "false"}))
+			Expect(director.Uploads).To(BeEmpty())
+		})
+
+		Context("when the fetched stemcell has no metadata.json", func() {
+			BeforeEach(func() {
+				Expect(os.Remove(filepath.Join(sourcesDir, "stemcell", "metadata.json"))).To(Succeed())
+			})
+
+			It("falls back to the name of the source", func() {
+				outRequest.Source.Name = "bosh-aws-xen-hvm-ubuntu-jammy-go_agent"
+				director.Stemcells = []map[string]string{{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", "version": "1.1"}}
+
+				response, err := put(client, outRequest, sourcesDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.Metadata).To(ContainElement(concourseMetadataField{Name: "uploaded", Value: "false"}))
+			})
+
+			It("resolves the name published for the iaas and os of the source", func() {
+				outRequest.Source.IaaS = "aws"
+				outRequest.Source.OS = "ubuntu-jammy"
+				director.Stemcells = []map[string]string{{"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent", "version": "1.1"}}
+
+				response, err := put(client, outRequest, sourcesDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.Metadata).To(ContainElements(
+					concourseMetadataField{Name: "name", Value: "bosh-aws-xen-hvm-ubuntu-jammy-go_agent"},
+					concourseMetadataField{Name: "uploaded", Value: "false"},
+				))
+			})
+
+			It("returns an error when the iaas and os match several names", func() {
+				outRequest.Source.OS = "ubuntu-jammy"
+
+				_, err := put(client, outRequest, sourcesDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to determine the stemcell name: several stemcells are published on bosh.io for iaas '' and os 'ubuntu-jammy'")))
+			})
+
+			It("returns an error without a name in the source", func() {
+				_, err := put(client, outRequest, sourcesDir)
+				Expect(err).To(MatchError("failed to determine the stemcell name: the fetched stemcell has no metadata.json and neither source.name nor source.iaas and source.os are set"))
+			})
+		})
+
+		Context("when there are several tarballs", func() {
+			It("returns an error", func() {
+				writeFile("stemcell.tgz", "some-tarball")
+				writeFile("other.tgz", "other-tarball")
+
+				_, err := put(client, outRequest, sourcesDir)
+				Expect(err).To(MatchError(ContainSubstring("found several tarballs in")))
+				Expect(director.Uploads).To(BeEmpty())
+			})
+		})
+
+		Context("when a mirror is configured", func() {
+			var (
+				s3Backend *s3mem.Backend
+				s3Server  *httptest.Server
+			)
+
+			BeforeEach(func() {
+				s3Backend = s3mem.New()
+				Expect(s3Backend.CreateBucket("bucket_name")).To(Succeed())
+				s3Server = httptest.NewServer(gofakes3.New(s3Backend).Server())
+
+				outRequest.Source.Director.URL = ""
+				outRequest.Source.Mirror.URL = s3Server.URL
+				outRequest.Source.Mirror.Bucket = "bucket_name"
+				outRequest.Source.Auth.AccessKey = "access key"
+				outRequest.Source.Auth.SecretKey = "secret key"
+				Expect(os.Remove(filepath.Join(sourcesDir, "stemcell", "sha1"))).To(Succeed())
+			})
+
+			AfterEach(func() {
+				s3Server.Close()
+			})
+
+			It("mirrors the tarball under the name from metadata.json", func() {
+				writeFile("stemcell.tgz", "some-tarball")
+
+				response, err := put(client, outRequest, sourcesDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				key := "bosh-google-kvm-ubuntu-jammy-go_agent/1.1/stemcell.tgz"
+				Expect(response.Metadata).To(ContainElements(
+					concourseMetadataField{Name: "mirror_url", Value: s3Server.URL + "/bucket_name/" + key},
+					concourseMetadataField{Name: "mirrored", Value: "true"},
+				))
+
+				_, err = s3Backend.HeadObject("bucket_name", key)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when neither a director nor a mirror is configured", func() {
+			It("returns an error", func() {
+				outRequest.Source.Director.URL = ""
+
+				_, err := put(client, outRequest, sourcesDir)
+				Expect(err).To(MatchError("put step is not supported for this resource without a director or mirror"))
+			})
+		})
+	})
+})
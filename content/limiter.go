@@ -0,0 +1,80 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+//go:generate counterfeiter -o ../fakes/clock.go --fake-name Clock . Clock
+type Clock interface {
+	Now() time.Time
+	SleepUntil(t time.Time)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) SleepUntil(t time.Time) {
+	time.Sleep(time.Until(t))
+}
+
+// Limiter is a token bucket shared by concurrent downloads, bounding their
+// aggregate throughput.
+type Limiter struct {
+	bytesPerSecond float64
+	clock          Clock
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	return NewLimiterWithClock(bytesPerSecond, realClock{})
+}
+
+func NewLimiterWithClock(bytesPerSecond int64, clock Clock) *Limiter {
+	return &Limiter{
+		bytesPerSecond: float64(bytesPerSecond),
+		clock:          clock,
+	}
+}
+
+// Wait blocks until n more bytes can be transferred without exceeding the
+// bandwidth. Each caller reserves its share of the schedule, so that callers
+// waiting at the same time are spread out rather than released together.
+func (l *Limiter) Wait(n int) {
+	l.mutex.Lock()
+
+	now := l.clock.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.bytesPerSecond * float64(time.Second)))
+	deadline := l.next
+
+	l.mutex.Unlock()
+
+	l.clock.SleepUntil(deadline)
+}
+
+// ParseBandwidth parses a bandwidth such as 50MiB/s or 10MB/s into bytes per
+// second.
+func ParseBandwidth(bandwidth string) (int64, error) {
+	bytesPerSecond, err := humanize.ParseBytes(strings.TrimSuffix(strings.TrimSpace(bandwidth), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse bandwidth '%s': %s", bandwidth, err)
+	}
+
+	if bytesPerSecond == 0 {
+		return 0, fmt.Errorf("failed to parse bandwidth '%s': must be greater than zero", bandwidth)
+	}
+
+	return int64(bytesPerSecond), nil
+}
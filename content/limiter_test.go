@@ -0,0 +1,92 @@
+package content_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/content"
+	"github.com/concourse/bosh-io-stemcell-resource/fakes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	var (
+		clock *fakes.Clock
+		start time.Time
+	)
+
+	// lastDeadline is the latest time any caller slept until, which is when
+	// the concurrent sleeps on a real clock would be over.
+	lastDeadline := func() time.Time {
+		var deadline time.Time
+		for i := 0; i < clock.SleepUntilCallCount(); i++ {
+			if clock.SleepUntilArgsForCall(i).After(deadline) {
+				deadline = clock.SleepUntilArgsForCall(i)
+			}
+		}
+		return deadline
+	}
+
+	BeforeEach(func() {
+		start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock = &fakes.Clock{}
+		clock.NowReturns(start)
+	})
+
+	It("bounds the aggregate throughput of concurrent callers", func() {
+		limiter := content.NewLimiterWithClock(1024*1024, clock)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 32; j++ {
+					limiter.Wait(32 * 1024)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// 10MiB at 1MiB/s
+		Expect(lastDeadline().Sub(start)).To(Equal(10 * time.Second))
+	})
+
+	It("does not save up bandwidth while idle", func() {
+		limiter := content.NewLimiterWithClock(1000, clock)
+
+		limiter.Wait(1000)
+		Expect(lastDeadline().Sub(start)).To(Equal(time.Second))
+
+		clock.NowReturns(start.Add(time.Minute))
+
+		limiter.Wait(500)
+		Expect(lastDeadline().Sub(start)).To(Equal(time.Minute + 500*time.Millisecond))
+	})
+})
+
+var _ = Describe("ParseBandwidth", func() {
+	DescribeTable("parses bandwidths",
+		func(bandwidth string, expected int64) {
+			bytesPerSecond, err := content.ParseBandwidth(bandwidth)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bytesPerSecond).To(Equal(expected))
+		},
+		Entry("binary units", "50MiB/s", int64(50*1024*1024)),
+		Entry("decimal units", "10MB/s", int64(10*1000*1000)),
+		Entry("without the rate suffix", "1GiB", int64(1024*1024*1024)),
+		Entry("plain bytes", "1024", int64(1024)),
+	)
+
+	It("returns an error for an invalid bandwidth", func() {
+		_, err := content.ParseBandwidth("fast")
+		Expect(err).To(MatchError(ContainSubstring("failed to parse bandwidth 'fast'")))
+	})
+
+	It("returns an error for a zero bandwidth", func() {
+		_, err := content.ParseBandwidth("0MiB/s")
+		Expect(err).To(MatchError("failed to parse bandwidth '0MiB/s': must be greater than zero"))
+	})
+})
@@ -0,0 +1,365 @@
+package director
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+)
+
+type Config struct {
+	URL          string `json:"url"`
+	Client       string `json:"client"`
+	ClientSecret string `json:"client_secret"`
+	CACert       string `json:"ca_cert"`
+	TaskTimeout  string `json:"task_timeout"`
+}
+
+type Client struct {
+	url          string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	authType     string
+	uaaURL       string
+	token        string
+	TaskWait     time.Duration
+	TaskTimeout  time.Duration
+}
+
+type info struct {
+	UserAuthentication struct {
+		Type    string `json:"type"`
+		Options struct {
+			URL string `json:"url"`
+		} `json:"options"`
+	} `json:"user_authentication"`
+}
+
+type stemcell struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+const defaultTaskTimeout = time.Hour
+
+type task struct {
+	ID     int    `json:"id"`
+	State  string `json:"state"`
+	Result string `json:"result"`
+}
+
+// NewClient returns a client of the director, reached through the transport
+// of the resource so that its proxy settings apply. The ca_cert of the
+// director is trusted in addition to the ones of the transport.
+func NewClient(config Config, transportConfig boshio.TransportConfig) (*Client, error) {
+	if config.URL == "" {
+		return nil, errors.New("director url must be provided")
+	}
+
+	transport, err := transportConfig.Transport()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.CACert != "" {
+		pool := transport.TLSClientConfig.RootCAs
+		if pool == nil {
+			pool, err = x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+		}
+		if !pool.AppendCertsFromPEM([]byte(config.CACert)) {
+			return nil, errors.New("failed to parse director ca_cert")
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	taskTimeout := defaultTaskTimeout
+	if config.TaskTimeout != "" {
+		taskTimeout, err = time.ParseDuration(config.TaskTimeout)
+		if err != nil || taskTimeout <= 0 {
+			return nil, fmt.Errorf("failed to parse director task_timeout '%s': must be a positive duration such as 30m", config.TaskTimeout)
+		}
+	}
+
+	return &Client{
+		url:          strings.TrimSuffix(config.URL, "/"),
+		clientID:     config.Client,
+		clientSecret: config.ClientSecret,
+		httpClient: &http.Client{
+			Transport: transport,
+			// the director answers task creating requests with a redirect to
+			// the task, which is polled explicitly
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		TaskWait:    time.Second,
+		TaskTimeout: taskTimeout,
+	}, nil
+}
+
+// HasStemcell reports whether the director already has the given stemcell.
+func (c *Client) HasStemcell(name string, version string) (bool, error) {
+	req, err := http.NewRequest("GET", c.url+"/stemcells", nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed listing stemcells - director returned: %d", resp.StatusCode)
+	}
+
+	var stemcells []stemcell
+	err = json.NewDecoder(resp.Body).Decode(&stemcells)
+	if err != nil {
+		return false, fmt.Errorf("failed decoding stemcells: %s", err)
+	}
+
+	for _, s := range stemcells {
+		if s.Name == name && s.Version == version {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// UploadStemcell uploads a stemcell tarball to the director and waits for the
+// upload task to finish.
+func (c *Client) UploadStemcell(tarball io.Reader, size int64) error {
+	req, err := http.NewRequest("POST", c.url+"/stemcells", tarball)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/x-compressed")
+
+	// a tarball read from a file can be sent again with a new token
+	if seeker, ok := tarball.(io.ReadSeeker); ok && req.GetBody == nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			_, err := seeker.Seek(0, io.SeekStart)
+			return io.NopCloser(seeker), err
+		}
+	}
+
+	return c.runTask(req)
+}
+
+// UploadStemcellURL asks the director to download the stemcell from a URL and
+// waits for the upload task to finish.
+func (c *Client) UploadStemcellURL(stemcellURL string, sha1 string) error {
+	body, err := json.Marshal(map[string]string{
+		"location": stemcellURL,
+		"sha1":     sha1,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url+"/stemcells", strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.runTask(req)
+}
+
+func (c *Client) runTask(req *http.Request) error {
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return fmt.Errorf("failed uploading stemcell - director returned: %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("failed parsing task location: %s", err)
+	}
+
+	// a task stuck on the director would otherwise hang the build
+	deadline := time.Now().Add(c.TaskTimeout)
+	for {
+		t, err := c.getTask(location.Path)
+		if err != nil {
+			return err
+		}
+
+		switch t.State {
+		case "done":
+			return nil
+		case "queued", "processing", "cancelling":
+			if time.Now().After(deadline) {
+				return fmt.Errorf("director task %d did not finish within %s, last state '%s'", t.ID, c.TaskTimeout, t.State)
+			}
+			time.Sleep(c.TaskWait)
+		default:
+			return fmt.Errorf("director task %d finished with state '%s': %s", t.ID, t.State, t.Result)
+		}
+	}
+}
+
+func (c *Client) getTask(path string) (task, error) {
+	req, err := http.NewRequest("GET", c.url+path, nil)
+	if err != nil {
+		return task{}, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return task{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return task{}, fmt.Errorf("failed fetching task - director returned: %d", resp.StatusCode)
+	}
+
+	var t task
+	err = json.NewDecoder(resp.Body).Decode(&t)
+	if err != nil {
+		return task{}, fmt.Errorf("failed decoding task: %s", err)
+	}
+
+	return t, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	err := c.authenticate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// UAA tokens expire within minutes, while a task may be polled for much
+	// longer, so an expired token is replaced once for requests which can be
+	// sent again
+	replayable := req.Body == nil || req.GetBody != nil
+	if resp.StatusCode != http.StatusUnauthorized || c.authType != "uaa" || !replayable {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	c.token, err = c.uaaToken(c.uaaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+c.token)
+
+	return c.httpClient.Do(retry)
+}
+
+func (c *Client) authenticate(req *http.Request) error {
+	if c.authType == "" {
+		i, err := c.info()
+		if err != nil {
+			return err
+		}
+
+		switch i.UserAuthentication.Type {
+		case "uaa":
+			c.uaaURL = i.UserAuthentication.Options.URL
+			c.token, err = c.uaaToken(c.uaaURL)
+			if err != nil {
+				return err
+			}
+		case "basic":
+		default:
+			return fmt.Errorf("unsupported director authentication type '%s'", i.UserAuthentication.Type)
+		}
+
+		c.authType = i.UserAuthentication.Type
+	}
+
+	if c.authType == "basic" {
+		req.SetBasicAuth(c.clientID, c.clientSecret)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return nil
+}
+
+func (c *Client) info() (info, error) {
+	resp, err := c.httpClient.Get(c.url + "/info")
+	if err != nil {
+		return info{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return info{}, fmt.Errorf("failed fetching director info - director returned: %d", resp.StatusCode)
+	}
+
+	var i info
+	err = json.NewDecoder(resp.Body).Decode(&i)
+	if err != nil {
+		return info{}, fmt.Errorf("failed decoding director info: %s", err)
+	}
+
+	return i, nil
+}
+
+func (c *Client) uaaToken(uaaURL string) (string, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(uaaURL, "/")+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed fetching uaa token - uaa returned: %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("failed decoding uaa token: %s", err)
+	}
+
+	return token.AccessToken, nil
+}
@@ -0,0 +1,305 @@
+package director_test
+
+import (
+	"encoding/pem"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/director"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Director", func() {
+	var (
+		fake   *fakeDirector
+		client *director.Client
+	)
+
+	BeforeEach(func() {
+		fake = newFakeDirector(false)
+
+		var err error
+		client, err = director.NewClient(director.Config{
+			URL:          fake.URL(),
+			Client:       "some-client",
+			ClientSecret: "some-secret",
+		}, boshio.TransportConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		client.TaskWait = time.Millisecond
+	})
+
+	AfterEach(func() {
+		fake.Close()
+	})
+
+	Describe("NewClient", func() {
+		Context("when no url is provided", func() {
+			It("returns an error", func() {
+				_, err := director.NewClient(director.Config{}, boshio.TransportConfig{})
+				Expect(err).To(MatchError("director url must be provided"))
+			})
+		})
+
+		Context("when the task_timeout is invalid", func() {
+			It("returns an error", func() {
+				_, err := director.NewClient(director.Config{URL: "https://example.com", TaskTimeout: "soon"}, boshio.TransportConfig{})
+				Expect(err).To(MatchError("failed to parse director task_timeout 'soon': must be a positive duration such as 30m"))
+			})
+		})
+
+		Context("when the ca_cert is invalid", func() {
+			It("returns an error", func() {
+				_, err := director.NewClient(director.Config{URL: "https://example.com", CACert: "not a cert"}, boshio.TransportConfig{})
+				Expect(err).To(MatchError("failed to parse director ca_cert"))
+			})
+		})
+	})
+
+	Describe("HasStemcell", func() {
+		BeforeEach(func() {
+			fake.Stemcells = []map[string]string{
+				{"name": "some-stemcell", "version": "1.1"},
+				{"name": "other-stemcell", "version": "2.2"},
+			}
+		})
+
+		It("returns true when the director has the stemcell", func() {
+			exists, err := client.HasStemcell("some-stemcell", "1.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("returns false when the director does not have the stemcell version", func() {
+			exists, err := client.HasStemcell("some-stemcell", "2.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		Context("when the director uses basic auth", func() {
+			BeforeEach(func() {
+				fake.AuthType = "basic"
+			})
+
+			It("authenticates with the client credentials", func() {
+				exists, err := client.HasStemcell("other-stemcell", "2.2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(exists).To(BeTrue())
+			})
+		})
+
+		Context("when the client credentials are wrong", func() {
+			It("returns an error", func() {
+				client, err := director.NewClient(director.Config{
+					URL:          fake.URL(),
+					Client:       "some-client",
+					ClientSecret: "wrong-secret",
+				}, boshio.TransportConfig{})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = client.HasStemcell("some-stemcell", "1.1")
+				Expect(err).To(MatchError("failed fetching uaa token - uaa returned: 401"))
+			})
+		})
+
+		Context("when the director uses an unsupported auth type", func() {
+			It("returns an error", func() {
+				fake.AuthType = "kerberos"
+
+				_, err := client.HasStemcell("some-stemcell", "1.1")
+				Expect(err).To(MatchError("unsupported director authentication type 'kerberos'"))
+			})
+		})
+	})
+
+	Describe("UploadStemcell", func() {
+		It("uploads the tarball and waits for the task to finish", func() {
+			err := client.UploadStemcell(strings.NewReader("some-tarball"), int64(len("some-tarball")))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fake.Uploads).To(Equal([]uploadedStemcell{
+				{ContentType: "application/x-compressed", Body: "some-tarball"},
+			}))
+			Expect(fake.taskPolls).To(Equal(2))
+		})
+
+		Context("when the uaa token expires while the task is polled", func() {
+			It("fetches a new token and keeps polling", func() {
+				fake.TaskStates = []string{"queued", "processing", "processing", "done"}
+				fake.ExpireTokenAfterPolls = 1
+
+				err := client.UploadStemcell(strings.NewReader("some-tarball"), int64(len("some-tarball")))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fake.TokensIssued).To(Equal(2))
+				Expect(fake.taskPolls).To(Equal(3))
+			})
+		})
+
+		Context("when the uaa token expired before the upload", func() {
+			It("uploads the tarball again with a new token", func() {
+				_, err := client.HasStemcell("some-stemcell", "1.1")
+				Expect(err).NotTo(HaveOccurred())
+				fake.validToken = ""
+
+				err = client.UploadStemcell(strings.NewReader("some-tarball"), int64(len("some-tarball")))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fake.TokensIssued).To(Equal(2))
+				Expect(fake.Uploads).To(Equal([]uploadedStemcell{
+					{ContentType: "application/x-compressed", Body: "some-tarball"},
+				}))
+			})
+
+			It("returns an error for a tarball which cannot be read again", func() {
+				_, err := client.HasStemcell("some-stemcell", "1.1")
+				Expect(err).NotTo(HaveOccurred())
+				fake.validToken = ""
+
+				err = client.UploadStemcell(io.MultiReader(strings.NewReader("some-tarball")), int64(len("some-tarball")))
+				Expect(err).To(MatchError("failed uploading stemcell - director returned: 401"))
+			})
+		})
+
+		Context("when the task fails", func() {
+			It("returns an error", func() {
+				fake.TaskStates = []string{"processing", "error"}
+
+				err := client.UploadStemcell(strings.NewReader("some-tarball"), int64(len("some-tarball")))
+				Expect(err).To(MatchError("director task 42 finished with state 'error': stemcell is corrupt"))
+			})
+		})
+
+		Context("when the task does not finish in time", func() {
+			It("returns an error", func() {
+				fake.TaskStates = []string{"queued", "processing"}
+				client.TaskTimeout = 20 * time.Millisecond
+
+				err := client.UploadStemcell(strings.NewReader("some-tarball"), int64(len("some-tarball")))
+				Expect(err).To(MatchError("director task 42 did not finish within 20ms, last state 'processing'"))
+			})
+		})
+	})
+
+	Describe("UploadStemcellURL", func() {
+		It("asks the director to fetch the url", func() {
+			err := client.UploadStemcellURL("https://example.com/stemcell.tgz", "some-sha1")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fake.Uploads).To(HaveLen(1))
+			Expect(fake.Uploads[0].ContentType).To(Equal("application/json"))
+			Expect(fake.Uploads[0].Body).To(MatchJSON(`{"location":"https://example.com/stemcell.tgz","sha1":"some-sha1"}`))
+		})
+
+		Context("when the uaa token expired before the upload", func() {
+			It("uploads with a new token", func() {
+				_, err := client.HasStemcell("some-stemcell", "1.1")
+				Expect(err).NotTo(HaveOccurred())
+				fake.validToken = ""
+
+				err = client.UploadStemcellURL("https://example.com/stemcell.tgz", "some-sha1")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fake.TokensIssued).To(Equal(2))
+				Expect(fake.Uploads).To(HaveLen(1))
+			})
+		})
+	})
+
+	Context("when a proxy is configured", func() {
+		var p *proxy
+
+		BeforeEach(func() {
+			fake.AdvertisedURL = "http://director.test"
+			p = newProxy(fake.URL())
+		})
+
+		AfterEach(func() {
+			p.Close()
+		})
+
+		It("sends the requests to the director and its uaa through the proxy", func() {
+			client, err := director.NewClient(director.Config{
+				URL:          "http://director.test",
+				Client:       "some-client",
+				ClientSecret: "some-secret",
+			}, boshio.TransportConfig{Proxy: p.URL()})
+			Expect(err).NotTo(HaveOccurred())
+			client.TaskWait = time.Millisecond
+
+			err = client.UploadStemcell(strings.NewReader("some-tarball"), int64(len("some-tarball")))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(p.Requests()).To(Equal([]string{
+				"GET http://director.test/info",
+				"POST http://director.test/oauth/token",
+				"POST http://director.test/stemcells",
+				"GET http://director.test/tasks/42",
+				"GET http://director.test/tasks/42",
+				"GET http://director.test/tasks/42",
+			}))
+		})
+
+		It("connects directly to hosts matching no_proxy", func() {
+			client, err := director.NewClient(director.Config{URL: "http://director.test"}, boshio.TransportConfig{Proxy: p.URL(), NoProxy: []string{".test"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.HasStemcell("some-stemcell", "1.1")
+			Expect(err).To(MatchError(ContainSubstring("director.test")))
+
+			Expect(p.Requests()).To(BeEmpty())
+		})
+	})
+
+	Context("when the director uses TLS", func() {
+		var tlsFake *fakeDirector
+
+		BeforeEach(func() {
+			tlsFake = newFakeDirector(true)
+		})
+
+		AfterEach(func() {
+			tlsFake.Close()
+		})
+
+		It("trusts the configured ca_cert", func() {
+			caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsFake.s.Certificate().Raw})
+
+			client, err := director.NewClient(director.Config{
+				URL:          tlsFake.URL(),
+				Client:       "some-client",
+				ClientSecret: "some-secret",
+				CACert:       string(caCert),
+			}, boshio.TransportConfig{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.HasStemcell("some-stemcell", "1.1")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("trusts the ca_cert of the source", func() {
+			caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsFake.s.Certificate().Raw})
+
+			client, err := director.NewClient(director.Config{
+				URL:          tlsFake.URL(),
+				Client:       "some-client",
+				ClientSecret: "some-secret",
+			}, boshio.TransportConfig{CACert: string(caCert)})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.HasStemcell("some-stemcell", "1.1")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("fails without the ca_cert", func() {
+			client, err := director.NewClient(director.Config{URL: tlsFake.URL()}, boshio.TransportConfig{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.HasStemcell("some-stemcell", "1.1")
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+		})
+	})
+})
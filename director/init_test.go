@@ -0,0 +1,202 @@
+package director_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDirector(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Director Suite")
+}
+
+type uploadedStemcell struct {
+	ContentType string
+	Body        string
+}
+
+// fakeDirector is a minimal stand-in for the BOSH director and its UAA. Each
+// token request issues a new token, which replaces the previous one.
+type fakeDirector struct {
+	// AdvertisedURL is the url of the director and its UAA in the responses,
+	// which defaults to the url of the server.
+	AdvertisedURL string
+	AuthType      string
+	TaskStates    []string
+	Stemcells     []map[string]string
+	Uploads       []uploadedStemcell
+	// ExpireTokenAfterPolls revokes the current token once the task has been
+	// polled that many times.
+	ExpireTokenAfterPolls int
+	TokensIssued          int
+
+	mutex      sync.Mutex
+	taskPolls  int
+	validToken string
+	s          *httptest.Server
+}
+
+func newFakeDirector(tls bool) *fakeDirector {
+	d := &fakeDirector{
+		AuthType:   "uaa",
+		TaskStates: []string{"queued", "processing", "done"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info", d.info)
+	mux.HandleFunc("/oauth/token", d.token)
+	mux.HandleFunc("/stemcells", d.authorized(d.stemcells))
+	mux.HandleFunc("/tasks/42", d.authorized(d.task))
+
+	if tls {
+		d.s = httptest.NewTLSServer(mux)
+	} else {
+		d.s = httptest.NewServer(mux)
+	}
+
+	return d
+}
+
+func (d *fakeDirector) URL() string {
+	return d.s.URL
+}
+
+func (d *fakeDirector) Close() {
+	d.s.Close()
+}
+
+func (d *fakeDirector) advertisedURL() string {
+	if d.AdvertisedURL != "" {
+		return d.AdvertisedURL
+	}
+	return d.s.URL
+}
+
+func (d *fakeDirector) info(w http.ResponseWriter, req *http.Request) {
+	fmt.Fprintf(w, `{"user_authentication":{"type":"%s","options":{"url":"%s"}}}`, d.AuthType, d.advertisedURL())
+}
+
+func (d *fakeDirector) token(w http.ResponseWriter, req *http.Request) {
+	client, secret, ok := req.BasicAuth()
+	if !ok || client != "some-client" || secret != "some-secret" || req.FormValue("grant_type") != "client_credentials" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.TokensIssued++
+	d.validToken = fmt.Sprintf("token-%d", d.TokensIssued)
+	fmt.Fprintf(w, `{"access_token":"%s","token_type":"bearer","expires_in":600}`, d.validToken)
+}
+
+func (d *fakeDirector) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		d.mutex.Lock()
+		validToken := d.validToken
+		d.mutex.Unlock()
+
+		client, secret, ok := req.BasicAuth()
+		basicOK := d.AuthType == "basic" && ok && client == "some-client" && secret == "some-secret"
+		bearerOK := d.AuthType == "uaa" && validToken != "" && req.Header.Get("Authorization") == "Bearer "+validToken
+
+		if !basicOK && !bearerOK {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		handler(w, req)
+	}
+}
+
+func (d *fakeDirector) stemcells(w http.ResponseWriter, req *http.Request) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if req.Method == "GET" {
+		json.NewEncoder(w).Encode(d.Stemcells)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	Expect(err).NotTo(HaveOccurred())
+
+	d.Uploads = append(d.Uploads, uploadedStemcell{
+		ContentType: req.Header.Get("Content-Type"),
+		Body:        string(body),
+	})
+
+	w.Header().Set("Location", d.advertisedURL()+"/tasks/42")
+	w.WriteHeader(http.StatusFound)
+}
+
+func (d *fakeDirector) task(w http.ResponseWriter, req *http.Request) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	state := d.TaskStates[d.taskPolls]
+	if d.taskPolls < len(d.TaskStates)-1 {
+		d.taskPolls++
+	}
+	if d.ExpireTokenAfterPolls > 0 && d.taskPolls == d.ExpireTokenAfterPolls {
+		d.validToken = ""
+	}
+
+	result := ""
+	if state == "error" {
+		result = "stemcell is corrupt"
+	}
+
+	fmt.Fprintf(w, `{"id":42,"state":"%s","result":"%s"}`, state, result)
+}
+
+// proxy is a stand-in for a forward proxy which records every request it
+// receives and forwards it to the target server.
+type proxy struct {
+	mutex    sync.Mutex
+	requests []string
+	s        *httptest.Server
+}
+
+func newProxy(target string) *proxy {
+	targetURL, err := url.Parse(target)
+	Expect(err).NotTo(HaveOccurred())
+
+	p := &proxy{}
+	forward := httputil.NewSingleHostReverseProxy(targetURL)
+
+	p.s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p.mutex.Lock()
+		p.requests = append(p.requests, req.Method+" "+req.URL.String())
+		p.mutex.Unlock()
+
+		forward.ServeHTTP(w, req)
+	}))
+
+	return p
+}
+
+func (p *proxy) URL() string {
+	return p.s.URL
+}
+
+func (p *proxy) Requests() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string{}, p.requests...)
+}
+
+func (p *proxy) Close() {
+	p.s.Close()
+}
@@ -0,0 +1,98 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/content"
+)
+
+type Clock struct {
+	NowStub        func() time.Time
+	nowMutex       sync.RWMutex
+	nowArgsForCall []struct{}
+	nowReturns     struct {
+		result1 time.Time
+	}
+	SleepUntilStub        func(t time.Time)
+	sleepUntilMutex       sync.RWMutex
+	sleepUntilArgsForCall []struct {
+		t time.Time
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *Clock) Now() time.Time {
+	fake.nowMutex.Lock()
+	fake.nowArgsForCall = append(fake.nowArgsForCall, struct{}{})
+	fake.recordInvocation("Now", []interface{}{})
+	fake.nowMutex.Unlock()
+	if fake.NowStub != nil {
+		return fake.NowStub()
+	} else {
+		return fake.nowReturns.result1
+	}
+}
+
+func (fake *Clock) NowCallCount() int {
+	fake.nowMutex.RLock()
+	defer fake.nowMutex.RUnlock()
+	return len(fake.nowArgsForCall)
+}
+
+func (fake *Clock) NowReturns(result1 time.Time) {
+	fake.NowStub = nil
+	fake.nowReturns = struct {
+		result1 time.Time
+	}{result1}
+}
+
+func (fake *Clock) SleepUntil(t time.Time) {
+	fake.sleepUntilMutex.Lock()
+	fake.sleepUntilArgsForCall = append(fake.sleepUntilArgsForCall, struct {
+		t time.Time
+	}{t})
+	fake.recordInvocation("SleepUntil", []interface{}{t})
+	fake.sleepUntilMutex.Unlock()
+	if fake.SleepUntilStub != nil {
+		fake.SleepUntilStub(t)
+	}
+}
+
+func (fake *Clock) SleepUntilCallCount() int {
+	fake.sleepUntilMutex.RLock()
+	defer fake.sleepUntilMutex.RUnlock()
+	return len(fake.sleepUntilArgsForCall)
+}
+
+func (fake *Clock) SleepUntilArgsForCall(i int) time.Time {
+	fake.sleepUntilMutex.RLock()
+	defer fake.sleepUntilMutex.RUnlock()
+	return fake.sleepUntilArgsForCall[i].t
+}
+
+func (fake *Clock) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.nowMutex.RLock()
+	defer fake.nowMutex.RUnlock()
+	fake.sleepUntilMutex.RLock()
+	defer fake.sleepUntilMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *Clock) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ content.Clock = new(Clock)
@@ -0,0 +1,58 @@
+// This file was generated by counterfeiter
+package fakes
+
+import "sync"
+
+type Limiter struct {
+	WaitStub        func(n int)
+	waitMutex       sync.RWMutex
+	waitArgsForCall []struct {
+		n int
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *Limiter) Wait(n int) {
+	fake.waitMutex.Lock()
+	fake.waitArgsForCall = append(fake.waitArgsForCall, struct {
+		n int
+	}{n})
+	fake.recordInvocation("Wait", []interface{}{n})
+	fake.waitMutex.Unlock()
+	if fake.WaitStub != nil {
+		fake.WaitStub(n)
+	}
+}
+
+func (fake *Limiter) WaitCallCount() int {
+	fake.waitMutex.RLock()
+	defer fake.waitMutex.RUnlock()
+	return len(fake.waitArgsForCall)
+}
+
+func (fake *Limiter) WaitArgsForCall(i int) int {
+	fake.waitMutex.RLock()
+	defer fake.waitMutex.RUnlock()
+	return fake.waitArgsForCall[i].n
+}
+
+func (fake *Limiter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.waitMutex.RLock()
+	defer fake.waitMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *Limiter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
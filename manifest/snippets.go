@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OpsFile           = "stemcell-ops.yml"
+	UploadFile        = "stemcell-upload.yml"
+	RuntimeConfigFile = "stemcell-runtime-config.yml"
+)
+
+type opsFileEntry struct {
+	Type  string        `yaml:"type"`
+	Path  string        `yaml:"path"`
+	Value manifestEntry `yaml:"value"`
+}
+
+type manifestEntry struct {
+	Alias   string `yaml:"alias"`
+	OS      string `yaml:"os"`
+	Version string `yaml:"version"`
+}
+
+type uploadEntry struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	URL     string `yaml:"url"`
+	SHA1    string `yaml:"sha1"`
+}
+
+type runtimeConfigInclude struct {
+	Include struct {
+		Stemcell []stemcellInclude `yaml:"stemcell"`
+	} `yaml:"include"`
+}
+
+type stemcellInclude struct {
+	OS string `yaml:"os"`
+}
+
+// WriteSnippets writes BOSH manifest snippets for the stemcell to the
+// location:
+//   - an ops file replacing the default stemcell of a deployment manifest
+//   - the url and sha1 of the stemcell, like a release in a manifest
+//   - an include rule for runtime config addons matching the stemcell OS
+func WriteSnippets(stemcell boshio.Stemcell, location string) error {
+	stemcellOS, err := stemcell.OS()
+	if err != nil {
+		return err
+	}
+
+	ops := []opsFileEntry{{
+		Type: "replace",
+		Path: "/stemcells/alias=default",
+		Value: manifestEntry{
+			Alias:   "default",
+			OS:      stemcellOS,
+			Version: stemcell.Version,
+		},
+	}}
+
+	upload := uploadEntry{
+		Name:    stemcell.Name,
+		Version: stemcell.Version,
+		URL:     stemcell.Details().URL,
+		SHA1:    stemcell.Details().SHA1,
+	}
+
+	var runtimeConfig runtimeConfigInclude
+	runtimeConfig.Include.Stemcell = []stemcellInclude{{OS: stemcellOS}}
+
+	snippets := []struct {
+		fileName string
+		value    interface{}
+	}{
+		{OpsFile, ops},
+		{UploadFile, upload},
+		{RuntimeConfigFile, runtimeConfig},
+	}
+
+	for _, snippet := range snippets {
+		contents, err := yaml.Marshal(snippet.value)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(location, snippet.fileName), contents, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
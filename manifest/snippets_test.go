@@ -0,0 +1,101 @@
+package manifest_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/manifest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteSnippets", func() {
+	var (
+		location string
+		stemcell boshio.Stemcell
+	)
+
+	BeforeEach(func() {
+		var err error
+		location, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		stemcell = boshio.Stemcell{
+			Name:    "bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+			Version: "1.100",
+			Light: &boshio.Metadata{
+				URL:  "https://example.com/light-bosh-stemcell-1.100-aws-xen-hvm-ubuntu-jammy-go_agent.tgz",
+				SHA1: "2222",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(location)).To(Succeed())
+	})
+
+	readSnippet := func(fileName string) string {
+		contents, err := os.ReadFile(filepath.Join(location, fileName))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	It("writes an ops file replacing the default stemcell", func() {
+		Expect(manifest.WriteSnippets(stemcell, location)).To(Succeed())
+
+		Expect(readSnippet("stemcell-ops.yml")).To(MatchYAML(`
+- type: replace
+  path: /stemcells/alias=default
+  value:
+    alias: default
+    os: ubuntu-jammy
+    version: "1.100"
+`))
+	})
+
+	It("writes the url and sha1 to upload the stemcell", func() {
+		Expect(manifest.WriteSnippets(stemcell, location)).To(Succeed())
+
+		Expect(readSnippet("stemcell-upload.yml")).To(MatchYAML(`
+name: bosh-aws-xen-hvm-ubuntu-jammy-go_agent
+version: "1.100"
+url: https://example.com/light-bosh-stemcell-1.100-aws-xen-hvm-ubuntu-jammy-go_agent.tgz
+sha1: "2222"
+`))
+	})
+
+	It("writes a runtime config include rule for the stemcell os", func() {
+		Expect(manifest.WriteSnippets(stemcell, location)).To(Succeed())
+
+		Expect(readSnippet("stemcell-runtime-config.yml")).To(MatchYAML(`
+include:
+  stemcell:
+  - os: ubuntu-jammy
+`))
+	})
+
+	It("keeps the version a string", func() {
+		stemcell.Version = "1.10"
+		Expect(manifest.WriteSnippets(stemcell, location)).To(Succeed())
+
+		Expect(readSnippet("stemcell-ops.yml")).To(ContainSubstring(`version: "1.10"`))
+	})
+
+	Context("when the operating system cannot be determined", func() {
+		It("returns an error", func() {
+			stemcell.Name = "some-stemcell"
+
+			err := manifest.WriteSnippets(stemcell, location)
+			Expect(err).To(MatchError("failed to determine the operating system of stemcell 'some-stemcell'"))
+		})
+	})
+
+	Context("when the location is not writeable", func() {
+		It("returns an error", func() {
+			err := manifest.WriteSnippets(stemcell, filepath.Join(location, "missing"))
+			Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package mirror_test
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMirror(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mirror Suite")
+}
+
+var (
+	s3Backend  *s3mem.Backend
+	s3Server   *httptest.Server
+	s3Requests *requestLog
+)
+
+var _ = BeforeEach(func() {
+	s3Backend = s3mem.New()
+	err := s3Backend.CreateBucket("bucket_name")
+	Expect(err).NotTo(HaveOccurred())
+
+	s3Requests = &requestLog{}
+	s3Server = httptest.NewServer(s3Requests.record(gofakes3.New(s3Backend).Server()))
+})
+
+var _ = AfterEach(func() {
+	s3Server.Close()
+})
+
+func md5Sum(b []byte) [16]byte {
+	return md5.Sum(b)
+}
+
+type noopBar struct{}
+
+func (noopBar) SetTotal(int64) {}
+func (noopBar) Add(n int) int  { return n }
+func (noopBar) Kickoff()       {}
+func (noopBar) Finish()        {}
+
+type singleRanger struct{}
+
+func (singleRanger) BuildRange(contentLength int64) ([]string, error) {
+	return []string{fmt.Sprintf("0-%d", contentLength-1)}, nil
+}
+
+// requestLog records the method and path of every request to the fake S3.
+type requestLog struct {
+	mutex    sync.Mutex
+	requests []string
+}
+
+func (l *requestLog) record(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		l.mutex.Lock()
+		l.requests = append(l.requests, req.Method+" "+req.URL.Path)
+		l.mutex.Unlock()
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func (l *requestLog) Puts() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var puts []string
+	for _, request := range l.requests {
+		if strings.HasPrefix(request, "PUT ") {
+			puts = append(puts, strings.TrimPrefix(request, "PUT "))
+		}
+	}
+	return puts
+}
+
+func (l *requestLog) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.requests = nil
+}
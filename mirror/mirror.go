@@ -0,0 +1,246 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const defaultPartSize = 64 * 1024 * 1024
+
+type Config struct {
+	URL      string `json:"url"`
+	Bucket   string `json:"bucket"`
+	Prefix   string `json:"prefix"`
+	PartSize uint64 `json:"part_size"`
+}
+
+type Mirror struct {
+	url      string
+	bucket   string
+	prefix   string
+	partSize uint64
+	client   *minio.Client
+}
+
+// Metadata describes a mirrored stemcell. It is stored as a JSON object next
+// to the tarball.
+type Metadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Size    int64  `json:"size"`
+	MD5     string `json:"md5"`
+	SHA1    string `json:"sha1"`
+	SHA256  string `json:"sha256"`
+}
+
+type Result struct {
+	Metadata Metadata
+	Skipped  bool
+}
+
+// New returns a mirror of the bucket, reached through the transport of the
+// resource so that its ca_cert and proxy settings apply.
+func New(config Config, auth boshio.Auth, transportConfig boshio.TransportConfig) (*Mirror, error) {
+	if config.URL == "" || config.Bucket == "" {
+		return nil, errors.New("mirror url and bucket must be provided")
+	}
+
+	parsedURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mirror url: %s", err)
+	}
+
+	transport, err := transportConfig.Transport()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := minio.New(parsedURL.Host, &minio.Options{
+		Creds:     credentials.NewStaticV4(auth.AccessKey, auth.SecretKey, ""),
+		Secure:    parsedURL.Scheme == "https",
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := config.PartSize
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+
+	return &Mirror{
+		url:      strings.TrimSuffix(config.URL, "/"),
+		bucket:   config.Bucket,
+		prefix:   config.Prefix,
+		partSize: partSize,
+		client:   client,
+	}, nil
+}
+
+// Upload copies the tarball, its checksums and a metadata object into the
+// bucket. The checksums are computed from the tarball and verified against the
+// expected ones when provided. Each object is only uploaded when the bucket
+// does not already hold it with the same sha256, so that a partially mirrored
+// stemcell is repaired without uploading the tarball again.
+func (m *Mirror) Upload(name string, version string, tarballPath string, expected boshio.Metadata) (Result, error) {
+	metadata, err := checksum(tarballPath)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if expected.SHA1 != "" && expected.SHA1 != metadata.SHA1 {
+		return Result{}, fmt.Errorf("computed sha1 %s did not match expected sha1 of %s", metadata.SHA1, expected.SHA1)
+	}
+
+	if expected.SHA256 != "" && expected.SHA256 != metadata.SHA256 {
+		return Result{}, fmt.Errorf("computed sha256 %s did not match expected sha256 of %s", metadata.SHA256, expected.SHA256)
+	}
+
+	fileName := filepath.Base(tarballPath)
+	key := path.Join(m.prefix, name, version, fileName)
+
+	metadata.Name = name
+	metadata.Version = version
+	metadata.URL = fmt.Sprintf("%s/%s/%s", m.url, m.bucket, key)
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return Result{}, err
+	}
+
+	objects := []object{
+		{
+			key:         key,
+			contentType: "application/x-compressed",
+			size:        metadata.Size,
+			sha1:        metadata.SHA1,
+			sha256:      metadata.SHA256,
+			open: func() (io.ReadCloser, error) {
+				return os.Open(tarballPath)
+			},
+		},
+		sidecar(key+".sha1", []byte(metadata.SHA1)),
+		sidecar(key+".sha256", []byte(metadata.SHA256)),
+		sidecar(path.Join(path.Dir(key), "metadata.json"), metadataJSON),
+	}
+
+	ctx := context.Background()
+	result := Result{Metadata: metadata, Skipped: true}
+
+	for _, o := range objects {
+		current, err := m.isCurrent(ctx, o)
+		if err != nil {
+			return Result{}, err
+		}
+		if current {
+			continue
+		}
+
+		err = m.put(ctx, o)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to upload %s: %s", path.Base(o.key), err)
+		}
+		result.Skipped = false
+	}
+
+	return result, nil
+}
+
+// object is a mirrored object along with the checksums of its contents, which
+// are stored in its user metadata.
+type object struct {
+	key         string
+	contentType string
+	size        int64
+	sha1        string
+	sha256      string
+	open        func() (io.ReadCloser, error)
+}
+
+func sidecar(key string, contents []byte) object {
+	return object{
+		key:    key,
+		size:   int64(len(contents)),
+		sha256: fmt.Sprintf("%x", sha256.Sum256(contents)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(contents)), nil
+		},
+	}
+}
+
+// isCurrent reports whether the bucket already holds the object with the same
+// contents.
+func (m *Mirror) isCurrent(ctx context.Context, o object) (bool, error) {
+	info, err := m.client.StatObject(ctx, m.bucket, o.key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat mirrored %s: %s", path.Base(o.key), err)
+	}
+
+	return info.UserMetadata["Sha256"] == o.sha256, nil
+}
+
+func (m *Mirror) put(ctx context.Context, o object) error {
+	contents, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer contents.Close()
+
+	userMetadata := map[string]string{"sha256": o.sha256}
+	if o.sha1 != "" {
+		userMetadata["sha1"] = o.sha1
+	}
+
+	_, err = m.client.PutObject(ctx, m.bucket, o.key, contents, o.size, minio.PutObjectOptions{
+		ContentType: o.contentType,
+		PartSize:    m.partSize,
+		// each part is verified by its Content-MD5 rather than a streaming
+		// signature, which not every S3 compatible store supports
+		SendContentMd5:       true,
+		DisableContentSha256: true,
+		UserMetadata:         userMetadata,
+	})
+	return err
+}
+
+func checksum(tarballPath string) (Metadata, error) {
+	tarball, err := os.Open(tarballPath)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer tarball.Close()
+
+	md5Hash, sha1Hash, sha256Hash := md5.New(), sha1.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha1Hash, sha256Hash), tarball)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return Metadata{
+		Size:   size,
+		MD5:    fmt.Sprintf("%x", md5Hash.Sum(nil)),
+		SHA1:   fmt.Sprintf("%x", sha1Hash.Sum(nil)),
+		SHA256: fmt.Sprintf("%x", sha256Hash.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,246 @@
+package mirror_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/mirror"
+	"github.com/johannesboyne/gofakes3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mirror", func() {
+	var (
+		m             *mirror.Mirror
+		tarballPath   string
+		tarball       []byte
+		tarballSHA1   string
+		tarballSHA256 string
+	)
+
+	readObject := func(key string) []byte {
+		obj, err := s3Backend.GetObject("bucket_name", key, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer obj.Contents.Close()
+
+		contents, err := io.ReadAll(obj.Contents)
+		Expect(err).NotTo(HaveOccurred())
+		return contents
+	}
+
+	BeforeEach(func() {
+		var err error
+		m, err = mirror.New(mirror.Config{
+			URL:    s3Server.URL,
+			Bucket: "bucket_name",
+			Prefix: "stemcells",
+		}, boshio.Auth{AccessKey: "access key", SecretKey: "secret key"}, boshio.TransportConfig{})
+		Expect(err).NotTo(HaveOccurred())
+
+		location, err := os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		tarball = []byte("this string is definitely not long enough to be 100 bytes but we get it there with a little bit of..")
+		tarballPath = filepath.Join(location, "light-bosh-stemcell.tgz")
+		err = os.WriteFile(tarballPath, tarball, 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		tarballSHA1 = fmt.Sprintf("%x", sha1.Sum(tarball))
+		tarballSHA256 = fmt.Sprintf("%x", sha256.Sum256(tarball))
+	})
+
+	Describe("New", func() {
+		Context("when the bucket is missing", func() {
+			It("returns an error", func() {
+				_, err := mirror.New(mirror.Config{URL: s3Server.URL}, boshio.Auth{}, boshio.TransportConfig{})
+				Expect(err).To(MatchError("mirror url and bucket must be provided"))
+			})
+		})
+	})
+
+	Describe("Upload", func() {
+		It("uploads the tarball, its checksums and metadata", func() {
+			result, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{SHA1: tarballSHA1, SHA256: tarballSHA256})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Skipped).To(BeFalse())
+			Expect(result.Metadata).To(Equal(mirror.Metadata{
+				Name:    "some-stemcell",
+				Version: "1.1",
+				URL:     s3Server.URL + "/bucket_name/stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz",
+				Size:    100,
+				MD5:     fmt.Sprintf("%x", md5Sum(tarball)),
+				SHA1:    tarballSHA1,
+				SHA256:  tarballSHA256,
+			}))
+
+			Expect(readObject("stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz")).To(Equal(tarball))
+			Expect(string(readObject("stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz.sha1"))).To(Equal(tarballSHA1))
+			Expect(string(readObject("stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz.sha256"))).To(Equal(tarballSHA256))
+
+			var metadata mirror.Metadata
+			err = json.Unmarshal(readObject("stemcells/some-stemcell/1.1/metadata.json"), &metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(Equal(result.Metadata))
+		})
+
+		It("can be downloaded again with the same auth", func() {
+			result, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+			Expect(err).NotTo(HaveOccurred())
+
+			location, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			client := boshio.NewClient(nil, noopBar{}, singleRanger{}, false)
+			_, err = client.DownloadStemcell(boshio.Stemcell{
+				Regular: &boshio.Metadata{URL: result.Metadata.URL, SHA256: result.Metadata.SHA256},
+			}, location, false, boshio.Auth{AccessKey: "access key", SecretKey: "secret key"})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(filepath.Join(location, "stemcell.tgz"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(tarball))
+		})
+
+		Context("when the tarball is larger than a part", func() {
+			It("uploads it in several parts", func() {
+				var err error
+				m, err = mirror.New(mirror.Config{
+					URL:      s3Server.URL,
+					Bucket:   "bucket_name",
+					PartSize: 5 * 1024 * 1024,
+				}, boshio.Auth{AccessKey: "access key", SecretKey: "secret key"}, boshio.TransportConfig{})
+				Expect(err).NotTo(HaveOccurred())
+
+				tarball = bytes.Repeat([]byte("stemcell"), 1536*1024)
+				err = os.WriteFile(tarballPath, tarball, 0644)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readObject("some-stemcell/1.1/light-bosh-stemcell.tgz")).To(Equal(tarball))
+			})
+		})
+
+		Context("when the stemcell was already mirrored", func() {
+			BeforeEach(func() {
+				_, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+				s3Requests.Reset()
+			})
+
+			It("skips the upload of every object", func() {
+				result, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Skipped).To(BeTrue())
+
+				Expect(s3Requests.Puts()).To(BeEmpty())
+			})
+
+			It("uploads a missing sidecar without the tarball", func() {
+				_, err := s3Backend.DeleteObject("bucket_name", "stemcells/some-stemcell/1.1/metadata.json")
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Skipped).To(BeFalse())
+
+				Expect(s3Requests.Puts()).To(Equal([]string{"/bucket_name/stemcells/some-stemcell/1.1/metadata.json"}))
+
+				var metadata mirror.Metadata
+				err = json.Unmarshal(readObject("stemcells/some-stemcell/1.1/metadata.json"), &metadata)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata).To(Equal(result.Metadata))
+			})
+
+			It("uploads a stale sidecar again", func() {
+				stale := []byte("stale")
+				staleMetadata := map[string]string{"X-Amz-Meta-Sha256": fmt.Sprintf("%x", sha256.Sum256(stale))}
+				_, err := s3Backend.PutObject("bucket_name", "stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz.sha256", staleMetadata, bytes.NewReader(stale), int64(len(stale)))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(s3Requests.Puts()).To(Equal([]string{"/bucket_name/stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz.sha256"}))
+				Expect(string(readObject("stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz.sha256"))).To(Equal(tarballSHA256))
+			})
+
+			It("uploads every object when the tarball changed", func() {
+				err := os.WriteFile(tarballPath, []byte("republished"), 0644)
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Skipped).To(BeFalse())
+
+				Expect(string(readObject("stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz"))).To(Equal("republished"))
+				Expect(string(readObject("stemcells/some-stemcell/1.1/light-bosh-stemcell.tgz.sha256"))).To(Equal(fmt.Sprintf("%x", sha256.Sum256([]byte("republished")))))
+			})
+		})
+
+		Context("when the mirror uses TLS", func() {
+			var tlsServer *httptest.Server
+
+			BeforeEach(func() {
+				tlsServer = httptest.NewTLSServer(gofakes3.New(s3Backend).Server())
+			})
+
+			AfterEach(func() {
+				tlsServer.Close()
+			})
+
+			It("trusts the ca_cert of the source", func() {
+				caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+
+				m, err := mirror.New(mirror.Config{URL: tlsServer.URL, Bucket: "bucket_name"},
+					boshio.Auth{AccessKey: "access key", SecretKey: "secret key"},
+					boshio.TransportConfig{CACert: string(caCert)})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(readObject("some-stemcell/1.1/light-bosh-stemcell.tgz")).To(Equal(tarball))
+			})
+
+			It("fails without the ca_cert", func() {
+				m, err := mirror.New(mirror.Config{URL: tlsServer.URL, Bucket: "bucket_name"},
+					boshio.Auth{AccessKey: "access key", SecretKey: "secret key"},
+					boshio.TransportConfig{})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).To(MatchError(ContainSubstring("certificate")))
+			})
+		})
+
+		Context("when the tarball does not match the expected checksum", func() {
+			It("returns an error", func() {
+				_, err := m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{SHA256: "4444"})
+				Expect(err).To(MatchError(fmt.Sprintf("computed sha256 %s did not match expected sha256 of 4444", tarballSHA256)))
+			})
+		})
+
+		Context("when the bucket does not exist", func() {
+			It("returns an error", func() {
+				m, err := mirror.New(mirror.Config{URL: s3Server.URL, Bucket: "missing"}, boshio.Auth{AccessKey: "access key", SecretKey: "secret key"}, boshio.TransportConfig{})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = m.Upload("some-stemcell", "1.1", tarballPath, boshio.Metadata{})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,60 @@
+package sbom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Package is an installed package of the stemcell, as listed by `dpkg -l`.
+type Package struct {
+	Name         string
+	Version      string
+	Architecture string
+	Description  string
+}
+
+// ParseDpkgList parses the installed packages of a `dpkg -l` listing such as
+// stemcell_dpkg_l.txt. Header lines and packages which are not installed are
+// skipped.
+func ParseDpkgList(r io.Reader) ([]Package, error) {
+	var packages []Package
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] != "ii" {
+			continue
+		}
+
+		name, arch, _ := strings.Cut(fields[1], ":")
+		if arch == "" {
+			arch = fields[3]
+		}
+
+		packages = append(packages, Package{
+			Name:         name,
+			Version:      fields[2],
+			Architecture: arch,
+			Description:  strings.Join(fields[4:], " "),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dpkg listing: %s", err)
+	}
+
+	return packages, nil
+}
+
+// PURL returns the package URL of the package within the given distro,
+// e.g. pkg:deb/ubuntu/adduser@3.118ubuntu5?arch=all&distro=ubuntu-jammy
+func (p Package) PURL(namespace string, distro string) string {
+	purl := fmt.Sprintf("pkg:deb/%s/%s@%s?arch=%s", namespace, url.QueryEscape(p.Name), url.QueryEscape(p.Version), url.QueryEscape(p.Architecture))
+	if distro != "" {
+		purl += "&distro=" + url.QueryEscape(distro)
+	}
+	return purl
+}
@@ -0,0 +1,54 @@
+package sbom_test
+
+import (
+	"strings"
+
+	"github.com/concourse/bosh-io-stemcell-resource/sbom"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const dpkgList = `Desired=Unknown/Install/Remove/Purge/Hold
+| Status=Not/Inst/Conf-files/Unpacked/halF-conf/Half-inst/trig-aWait/Trig-pend
+|/ Err?=(none)/Reinst-required (Status,Err: uppercase=bad)
+||/ Name           Version      Architecture Description
++++-==============-============-============-=================================
+ii  adduser        3.118ubuntu5 all          add and remove users and groups
+rc  old-package    1.0          amd64        removed package
+ii  libc6:amd64    2.35-0ubuntu3 amd64       GNU C Library: Shared libraries
+ii  libstdc++6     12.3.0-1ubuntu1~22.04 amd64 GNU Standard C++ Library v3
+`
+
+var _ = Describe("ParseDpkgList", func() {
+	It("returns the installed packages", func() {
+		packages, err := sbom.ParseDpkgList(strings.NewReader(dpkgList))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(packages).To(Equal([]sbom.Package{
+			{Name: "adduser", Version: "3.118ubuntu5", Architecture: "all", Description: "add and remove users and groups"},
+			{Name: "libc6", Version: "2.35-0ubuntu3", Architecture: "amd64", Description: "GNU C Library: Shared libraries"},
+			{Name: "libstdc++6", Version: "12.3.0-1ubuntu1~22.04", Architecture: "amd64", Description: "GNU Standard C++ Library v3"},
+		}))
+	})
+
+	It("returns no packages for an empty listing", func() {
+		packages, err := sbom.ParseDpkgList(strings.NewReader(""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(packages).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Package", func() {
+	Describe("PURL", func() {
+		It("returns the package url", func() {
+			p := sbom.Package{Name: "adduser", Version: "3.118ubuntu5", Architecture: "all"}
+			Expect(p.PURL("ubuntu", "ubuntu-jammy")).To(Equal("pkg:deb/ubuntu/adduser@3.118ubuntu5?arch=all&distro=ubuntu-jammy"))
+		})
+
+		It("escapes the name and version", func() {
+			p := sbom.Package{Name: "libstdc++6", Version: "1:12.3.0", Architecture: "amd64"}
+			Expect(p.PURL("ubuntu", "")).To(Equal("pkg:deb/ubuntu/libstdc%2B%2B6@1%3A12.3.0?arch=amd64"))
+		})
+	})
+})
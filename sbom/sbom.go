@@ -0,0 +1,260 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+)
+
+const (
+	SPDXFile      = "sbom.spdx.json"
+	CycloneDXFile = "sbom.cdx.json"
+
+	toolName = "bosh-io-stemcell-resource"
+)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	Summary               string            `json:"summary,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type        string          `json:"type"`
+	BOMRef      string          `json:"bom-ref,omitempty"`
+	Name        string          `json:"name"`
+	Version     string          `json:"version,omitempty"`
+	Description string          `json:"description,omitempty"`
+	PURL        string          `json:"purl,omitempty"`
+	Hashes      []cycloneDXHash `json:"hashes,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteSPDX writes an SPDX 2.3 JSON document describing the stemcell and the
+// packages it contains.
+func WriteSPDX(w io.Writer, stemcell boshio.Stemcell, packages []Package, created time.Time) error {
+	details := stemcell.Details()
+	namespace, distro := distroOf(stemcell)
+
+	root := spdxPackage{
+		SPDXID:                "SPDXRef-Stemcell",
+		Name:                  stemcell.Name,
+		VersionInfo:           stemcell.Version,
+		DownloadLocation:      details.URL,
+		FilesAnalyzed:         false,
+		PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		Checksums:             spdxChecksums(details),
+	}
+	if root.DownloadLocation == "" {
+		root.DownloadLocation = "NOASSERTION"
+	}
+
+	document := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              fmt.Sprintf("%s-%s", stemcell.Name, stemcell.Version),
+		DocumentNamespace: fmt.Sprintf("https://bosh.io/stemcells/%s/%s/%s", stemcell.Name, stemcell.Version, checksumOf(details)),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages: []spdxPackage{root},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: root.SPDXID,
+		}},
+	}
+
+	for i, p := range packages {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+
+		document.Packages = append(document.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             p.Name,
+			VersionInfo:      p.Version,
+			DownloadLocation: "NOASSERTION",
+			FilesAnalyzed:    false,
+			Summary:          p.Description,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  p.PURL(namespace, distro),
+			}},
+		})
+
+		document.Relationships = append(document.Relationships, spdxRelationship{
+			SPDXElementID:      root.SPDXID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	return encode(w, document)
+}
+
+// WriteCycloneDX writes a CycloneDX 1.5 JSON document describing the stemcell
+// and the packages it contains.
+func WriteCycloneDX(w io.Writer, stemcell boshio.Stemcell, packages []Package, created time.Time) error {
+	details := stemcell.Details()
+	namespace, distro := distroOf(stemcell)
+
+	root := cycloneDXComponent{
+		Type:    "operating-system",
+		BOMRef:  "stemcell",
+		Name:    stemcell.Name,
+		Version: stemcell.Version,
+		Hashes:  cycloneDXHashes(details),
+	}
+
+	document := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools: cycloneDXTools{
+				Components: []cycloneDXComponent{{Type: "application", Name: toolName}},
+			},
+			Component: root,
+		},
+		Components:   []cycloneDXComponent{},
+		Dependencies: []cycloneDXDependency{{Ref: root.BOMRef, DependsOn: []string{}}},
+	}
+
+	for _, p := range packages {
+		purl := p.PURL(namespace, distro)
+
+		document.Components = append(document.Components, cycloneDXComponent{
+			Type:        "library",
+			BOMRef:      purl,
+			Name:        p.Name,
+			Version:     p.Version,
+			Description: p.Description,
+			PURL:        purl,
+		})
+
+		document.Dependencies[0].DependsOn = append(document.Dependencies[0].DependsOn, purl)
+	}
+
+	return encode(w, document)
+}
+
+// distroOf returns the purl namespace and distro qualifier of the packages of
+// the stemcell, e.g. ubuntu and ubuntu-jammy.
+func distroOf(stemcell boshio.Stemcell) (string, string) {
+	stemcellOS, err := stemcell.OS()
+	if err != nil {
+		return "ubuntu", ""
+	}
+
+	namespace, _, _ := strings.Cut(stemcellOS, "-")
+	return namespace, stemcellOS
+}
+
+func checksumOf(details boshio.Metadata) string {
+	if details.SHA256 != "" {
+		return details.SHA256
+	}
+	return details.SHA1
+}
+
+func spdxChecksums(details boshio.Metadata) []spdxChecksum {
+	var checksums []spdxChecksum
+	if details.SHA256 != "" {
+		checksums = append(checksums, spdxChecksum{Algorithm: "SHA256", ChecksumValue: details.SHA256})
+	}
+	if details.SHA1 != "" {
+		checksums = append(checksums, spdxChecksum{Algorithm: "SHA1", ChecksumValue: details.SHA1})
+	}
+	return checksums
+}
+
+func cycloneDXHashes(details boshio.Metadata) []cycloneDXHash {
+	var hashes []cycloneDXHash
+	if details.SHA256 != "" {
+		hashes = append(hashes, cycloneDXHash{Alg: "SHA-256", Content: details.SHA256})
+	}
+	if details.SHA1 != "" {
+		hashes = append(hashes, cycloneDXHash{Alg: "SHA-1", Content: details.SHA1})
+	}
+	return hashes
+}
+
+func encode(w io.Writer, document interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
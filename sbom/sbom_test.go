@@ -0,0 +1,134 @@
+package sbom_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/sbom"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SBOM", func() {
+	var (
+		stemcell boshio.Stemcell
+		packages []sbom.Package
+		created  time.Time
+		output   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		stemcell = boshio.Stemcell{
+			Name:    "bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+			Version: "1.100",
+			Light: &boshio.Metadata{
+				URL:    "https://example.com/light-stemcell.tgz",
+				SHA1:   "2222",
+				SHA256: "4444",
+			},
+		}
+		packages = []sbom.Package{
+			{Name: "adduser", Version: "3.118ubuntu5", Architecture: "all", Description: "add and remove users and groups"},
+		}
+		created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		output = &bytes.Buffer{}
+	})
+
+	Describe("WriteSPDX", func() {
+		It("writes an SPDX document with the stemcell as the root package", func() {
+			err := sbom.WriteSPDX(output, stemcell, packages, created)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output.String()).To(MatchJSON(`{
+				"spdxVersion": "SPDX-2.3",
+				"dataLicense": "CC0-1.0",
+				"SPDXID": "SPDXRef-DOCUMENT",
+				"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent-1.100",
+				"documentNamespace": "https://bosh.io/stemcells/bosh-aws-xen-hvm-ubuntu-jammy-go_agent/1.100/4444",
+				"creationInfo": {
+					"created": "2024-01-02T03:04:05Z",
+					"creators": ["Tool: bosh-io-stemcell-resource"]
+				},
+				"packages": [
+					{
+						"SPDXID": "SPDXRef-Stemcell",
+						"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+						"versionInfo": "1.100",
+						"downloadLocation": "https://example.com/light-stemcell.tgz",
+						"filesAnalyzed": false,
+						"primaryPackagePurpose": "OPERATING-SYSTEM",
+						"checksums": [
+							{"algorithm": "SHA256", "checksumValue": "4444"},
+							{"algorithm": "SHA1", "checksumValue": "2222"}
+						]
+					},
+					{
+						"SPDXID": "SPDXRef-Package-1",
+						"name": "adduser",
+						"versionInfo": "3.118ubuntu5",
+						"downloadLocation": "NOASSERTION",
+						"filesAnalyzed": false,
+						"summary": "add and remove users and groups",
+						"externalRefs": [{
+							"referenceCategory": "PACKAGE-MANAGER",
+							"referenceType": "purl",
+							"referenceLocator": "pkg:deb/ubuntu/adduser@3.118ubuntu5?arch=all&distro=ubuntu-jammy"
+						}]
+					}
+				],
+				"relationships": [
+					{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Stemcell"},
+					{"spdxElementId": "SPDXRef-Stemcell", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-Package-1"}
+				]
+			}`))
+		})
+	})
+
+	Describe("WriteCycloneDX", func() {
+		It("writes a CycloneDX document with the stemcell as the root component", func() {
+			err := sbom.WriteCycloneDX(output, stemcell, packages, created)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output.String()).To(MatchJSON(`{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5",
+				"version": 1,
+				"metadata": {
+					"timestamp": "2024-01-02T03:04:05Z",
+					"tools": {"components": [{"type": "application", "name": "bosh-io-stemcell-resource"}]},
+					"component": {
+						"type": "operating-system",
+						"bom-ref": "stemcell",
+						"name": "bosh-aws-xen-hvm-ubuntu-jammy-go_agent",
+						"version": "1.100",
+						"hashes": [
+							{"alg": "SHA-256", "content": "4444"},
+							{"alg": "SHA-1", "content": "2222"}
+						]
+					}
+				},
+				"components": [{
+					"type": "library",
+					"bom-ref": "pkg:deb/ubuntu/adduser@3.118ubuntu5?arch=all&distro=ubuntu-jammy",
+					"name": "adduser",
+					"version": "3.118ubuntu5",
+					"description": "add and remove users and groups",
+					"purl": "pkg:deb/ubuntu/adduser@3.118ubuntu5?arch=all&distro=ubuntu-jammy"
+				}],
+				"dependencies": [{
+					"ref": "stemcell",
+					"dependsOn": ["pkg:deb/ubuntu/adduser@3.118ubuntu5?arch=all&distro=ubuntu-jammy"]
+				}]
+			}`))
+		})
+
+		It("writes an empty component list when there are no packages", func() {
+			err := sbom.WriteCycloneDX(output, stemcell, nil, created)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output.String()).To(ContainSubstring(`"components": []`))
+		})
+	})
+})
@@ -0,0 +1,224 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	ImageFile = "image"
+	ImageDir  = "image"
+)
+
+// diskSignatures are the magic bytes of the disk formats which can be declared
+// as the disk_format in the cloud_properties of stemcell.MF.
+var diskSignatures = map[string]func(*os.File) (bool, error){
+	"qcow2": hasPrefix([]byte("QFI\xfb")),
+	"vmdk":  hasPrefix([]byte("KDMV")),
+	"vhd":   hasFooter([]byte("conectix"), 512),
+}
+
+// ExtractImage streams the image out of the stemcell tarball into the image
+// directory of the location, unpacking it when it is itself a gzipped
+// tarball. The image is verified against the sha1 of stemcell.MF and, when its
+// cloud_properties declare a known disk_format, against the format of the
+// unpacked disk. The paths of the unpacked files relative to the image
+// directory are returned.
+func ExtractImage(tarballPath string, location string, manifest Manifest) ([]string, error) {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stemcell tarball: %s", err)
+	}
+	defer gzipReader.Close()
+
+	imageDir := filepath.Join(location, ImageDir)
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, errors.New("stemcell tarball does not contain an image")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stemcell tarball: %s", err)
+		}
+
+		if header.Typeflag != tar.TypeReg || path.Clean(header.Name) != ImageFile {
+			continue
+		}
+
+		// light stemcells ship an empty image referencing the IaaS image
+		if header.Size == 0 {
+			return nil, errors.New("stemcell tarball contains an empty image, light stemcells cannot be extracted")
+		}
+
+		err = os.MkdirAll(imageDir, 0755)
+		if err != nil {
+			return nil, err
+		}
+
+		imageSHA1 := sha1.New()
+		files, err := unpackImage(io.TeeReader(tarReader, imageSHA1), imageDir)
+		if err != nil {
+			return nil, err
+		}
+
+		// the image tarball may be padded past its end of archive marker,
+		// which still counts towards the sha1
+		_, err = io.Copy(io.Discard, io.TeeReader(tarReader, imageSHA1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stemcell image: %s", err)
+		}
+
+		computedSHA1 := fmt.Sprintf("%x", imageSHA1.Sum(nil))
+		if manifest.SHA1 != "" && computedSHA1 != manifest.SHA1 {
+			return nil, fmt.Errorf("computed image sha1 %s did not match the sha1 %s in stemcell.MF", computedSHA1, manifest.SHA1)
+		}
+
+		err = validateDiskFormat(imageDir, files, manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		return files, nil
+	}
+}
+
+// unpackImage writes the image to the directory, unpacking it when it is a
+// gzipped tarball.
+func unpackImage(r io.Reader, imageDir string) ([]string, error) {
+	bufferedReader := bufio.NewReader(r)
+
+	magic, err := bufferedReader.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stemcell image: %s", err)
+	}
+
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		err = writeFile(filepath.Join(imageDir, ImageFile), bufferedReader)
+		if err != nil {
+			return nil, err
+		}
+		return []string{ImageFile}, nil
+	}
+
+	gzipReader, err := gzip.NewReader(bufferedReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stemcell image: %s", err)
+	}
+	defer gzipReader.Close()
+
+	var files []string
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stemcell image: %s", err)
+		}
+
+		// rooting the name before cleaning it keeps entries such as
+		// ../../etc/passwd inside the image directory
+		name := path.Clean("/" + header.Name)[1:]
+		if name == "" {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(filepath.Join(imageDir, name), 0755)
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(filepath.Join(imageDir, name)), 0755)
+			if err == nil {
+				err = writeFile(filepath.Join(imageDir, name), tarReader)
+			}
+			files = append(files, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// validateDiskFormat ensures one of the unpacked files has the disk_format
+// declared in the cloud_properties. Formats without a known signature, such
+// as raw disks, are not checked.
+func validateDiskFormat(imageDir string, files []string, manifest Manifest) error {
+	diskFormat, _ := manifest.CloudProperties["disk_format"].(string)
+
+	matches, ok := diskSignatures[diskFormat]
+	if !ok {
+		return nil
+	}
+
+	for _, name := range files {
+		f, err := os.Open(filepath.Join(imageDir, name))
+		if err != nil {
+			return err
+		}
+
+		match, err := matches(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if match {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("stemcell image does not contain a %s disk as declared in the cloud_properties of stemcell.MF", diskFormat)
+}
+
+func hasPrefix(signature []byte) func(*os.File) (bool, error) {
+	return func(f *os.File) (bool, error) {
+		return hasSignatureAt(f, signature, 0)
+	}
+}
+
+func hasFooter(signature []byte, footerSize int64) func(*os.File) (bool, error) {
+	return func(f *os.File) (bool, error) {
+		info, err := f.Stat()
+		if err != nil {
+			return false, err
+		}
+		if info.Size() < footerSize {
+			return false, nil
+		}
+		return hasSignatureAt(f, signature, info.Size()-footerSize)
+	}
+}
+
+func hasSignatureAt(f *os.File, signature []byte, offset int64) (bool, error) {
+	buf := make([]byte, len(signature))
+	_, err := f.ReadAt(buf, offset)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(buf, signature), nil
+}
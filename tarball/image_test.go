@@ -0,0 +1,127 @@
+package tarball_test
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/bosh-io-stemcell-resource/tarball"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExtractImage", func() {
+	var (
+		location    string
+		tarballPath string
+		qcow2Disk   []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		location, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		tarballPath = filepath.Join(location, "stemcell.tgz")
+		qcow2Disk = append([]byte("QFI\xfb"), []byte("some disk")...)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(location)).To(Succeed())
+	})
+
+	manifestFor := func(image []byte, diskFormat string) tarball.Manifest {
+		return tarball.Manifest{
+			SHA1:            fmt.Sprintf("%x", sha1.Sum(image)),
+			CloudProperties: map[string]interface{}{"disk_format": diskFormat},
+		}
+	}
+
+	It("unpacks a nested image tarball into the image directory", func() {
+		image := gzippedTarball(
+			tarEntry{Name: "root.img", Contents: qcow2Disk},
+			tarEntry{Name: "../../escaped", Contents: []byte("contained")},
+		)
+		writeTarball(tarballPath,
+			tarEntry{Name: "stemcell.MF", Contents: []byte(stemcellMF)},
+			tarEntry{Name: "./image", Contents: image},
+		)
+
+		files, err := tarball.ExtractImage(tarballPath, location, manifestFor(image, "qcow2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{"escaped", "root.img"}))
+
+		disk, err := os.ReadFile(filepath.Join(location, "image", "root.img"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(disk).To(Equal(qcow2Disk))
+
+		escaped, err := os.ReadFile(filepath.Join(location, "image", "escaped"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(escaped)).To(Equal("contained"))
+	})
+
+	It("writes an image which is not a tarball as is", func() {
+		writeTarball(tarballPath, tarEntry{Name: "image", Contents: qcow2Disk})
+
+		files, err := tarball.ExtractImage(tarballPath, location, manifestFor(qcow2Disk, "qcow2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{"image"}))
+
+		disk, err := os.ReadFile(filepath.Join(location, "image", "image"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(disk).To(Equal(qcow2Disk))
+	})
+
+	It("does not check disk formats without a known signature", func() {
+		image := gzippedTarball(tarEntry{Name: "root.img", Contents: []byte("some raw disk")})
+		writeTarball(tarballPath, tarEntry{Name: "image", Contents: image})
+
+		_, err := tarball.ExtractImage(tarballPath, location, manifestFor(image, "raw"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the image does not match the sha1 in stemcell.MF", func() {
+		It("returns an error", func() {
+			writeTarball(tarballPath, tarEntry{Name: "image", Contents: qcow2Disk})
+
+			manifest := manifestFor(qcow2Disk, "qcow2")
+			manifest.SHA1 = "some-other-sha1"
+
+			_, err := tarball.ExtractImage(tarballPath, location, manifest)
+			Expect(err).To(MatchError(ContainSubstring("did not match the sha1 some-other-sha1 in stemcell.MF")))
+		})
+	})
+
+	Context("when the disk does not have the declared format", func() {
+		It("returns an error", func() {
+			image := gzippedTarball(tarEntry{Name: "root.img", Contents: qcow2Disk})
+			writeTarball(tarballPath, tarEntry{Name: "image", Contents: image})
+
+			_, err := tarball.ExtractImage(tarballPath, location, manifestFor(image, "vhd"))
+			Expect(err).To(MatchError("stemcell image does not contain a vhd disk as declared in the cloud_properties of stemcell.MF"))
+		})
+	})
+
+	Context("when the stemcell is light", func() {
+		It("returns an error", func() {
+			writeTarball(tarballPath,
+				tarEntry{Name: "stemcell.MF", Contents: []byte(stemcellMF)},
+				tarEntry{Name: "image", Contents: []byte{}},
+			)
+
+			_, err := tarball.ExtractImage(tarballPath, location, tarball.Manifest{})
+			Expect(err).To(MatchError("stemcell tarball contains an empty image, light stemcells cannot be extracted"))
+		})
+	})
+
+	Context("when the tarball has no image", func() {
+		It("returns an error", func() {
+			writeTarball(tarballPath, tarEntry{Name: "stemcell.MF", Contents: []byte(stemcellMF)})
+
+			_, err := tarball.ExtractImage(tarballPath, location, tarball.Manifest{})
+			Expect(err).To(MatchError("stemcell tarball does not contain an image"))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package tarball_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTarball(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tarball Suite")
+}
+
+type tarEntry struct {
+	Name     string
+	Contents []byte
+}
+
+// writeTarball writes a gzipped tarball with the given entries.
+func writeTarball(tarballPath string, entries ...tarEntry) {
+	Expect(os.WriteFile(tarballPath, gzippedTarball(entries...), 0644)).To(Succeed())
+}
+
+// gzippedTarball returns a gzipped tarball with the given entries.
+func gzippedTarball(entries ...tarEntry) []byte {
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, entry := range entries {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     entry.Name,
+			Mode:     0644,
+			Size:     int64(len(entry.Contents)),
+			Typeflag: tar.TypeReg,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tarWriter.Write(entry.Contents)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Expect(tarWriter.Close()).To(Succeed())
+	Expect(gzipWriter.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
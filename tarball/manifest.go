@@ -0,0 +1,155 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ManifestFile = "stemcell.MF"
+	DpkgFile     = "stemcell_dpkg_l.txt"
+	PackagesFile = "packages.txt"
+)
+
+// Manifest holds the fields of stemcell.MF, plus the agent version when it
+// is listed in the package manifests.
+type Manifest struct {
+	Name            string                 `yaml:"name"`
+	Version         string                 `yaml:"version"`
+	APIVersion      int                    `yaml:"api_version"`
+	SHA1            string                 `yaml:"sha1"`
+	OperatingSystem string                 `yaml:"operating_system"`
+	StemcellFormats []string               `yaml:"stemcell_formats"`
+	CloudProperties map[string]interface{} `yaml:"cloud_properties"`
+	AgentVersion    string                 `yaml:"-"`
+}
+
+// ExtractManifest streams through the stemcell tarball once, writing
+// stemcell.MF and the package manifests to the location.
+func ExtractManifest(tarballPath string, location string) (Manifest, error) {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read stemcell tarball: %s", err)
+	}
+	defer gzipReader.Close()
+
+	wanted := map[string]bool{ManifestFile: true, DpkgFile: true, PackagesFile: true}
+	found := map[string]bool{}
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to read stemcell tarball: %s", err)
+		}
+
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg || !wanted[name] {
+			continue
+		}
+
+		err = writeFile(filepath.Join(location, name), tarReader)
+		if err != nil {
+			return Manifest{}, err
+		}
+		found[name] = true
+	}
+
+	if !found[ManifestFile] {
+		return Manifest{}, errors.New("stemcell tarball does not contain a stemcell.MF")
+	}
+
+	contents, err := os.ReadFile(filepath.Join(location, ManifestFile))
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var manifest Manifest
+	err = yaml.Unmarshal(contents, &manifest)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse stemcell.MF: %s", err)
+	}
+
+	for _, packageFile := range []string{PackagesFile, DpkgFile} {
+		if !found[packageFile] {
+			continue
+		}
+
+		manifest.AgentVersion, err = findAgentVersion(filepath.Join(location, packageFile))
+		if err != nil {
+			return Manifest{}, err
+		}
+		if manifest.AgentVersion != "" {
+			break
+		}
+	}
+
+	return manifest, nil
+}
+
+// Validate ensures the manifest describes the requested stemcell.
+func (m Manifest) Validate(stemcell boshio.Stemcell) error {
+	if m.Name != stemcell.Name {
+		return fmt.Errorf("stemcell.MF name '%s' does not match the requested stemcell '%s'", m.Name, stemcell.Name)
+	}
+
+	if m.Version != stemcell.Version {
+		return fmt.Errorf("stemcell.MF version '%s' does not match the requested version '%s'", m.Version, stemcell.Version)
+	}
+
+	return nil
+}
+
+func writeFile(filePath string, r io.Reader) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// findAgentVersion looks for the bosh-agent in a package listing, either in
+// the `name version` format of packages.txt or in the `dpkg -l` format.
+func findAgentVersion(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == "ii" && fields[1] == "bosh-agent" {
+			return fields[2], nil
+		}
+		if len(fields) >= 2 && fields[0] == "bosh-agent" {
+			return fields[1], nil
+		}
+	}
+
+	return "", scanner.Err()
+}
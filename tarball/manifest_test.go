@@ -0,0 +1,150 @@
+package tarball_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/tarball"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const stemcellMF = `---
+name: bosh-aws-xen-hvm-ubuntu-jammy-go_agent
+version: '1.100'
+bosh_protocol: '1'
+api_version: 3
+sha1: da39a3ee5e6b4b0d3255bfef95601890afd80709
+operating_system: ubuntu-jammy
+stemcell_formats:
+- aws-light
+cloud_properties:
+  name: bosh-aws-xen-hvm-ubuntu-jammy-go_agent
+  version: '1.100'
+  disk: 5120
+`
+
+const dpkgList = `Desired=Unknown/Install/Remove/Purge/Hold
+||/ Name           Version      Architecture Description
++++-==============-============-============-=================================
+ii  adduser        3.118ubuntu5 all          add and remove users and groups
+ii  apt            2.4.9        amd64        commandline package manager
+`
+
+var _ = Describe("ExtractManifest", func() {
+	var (
+		location    string
+		tarballPath string
+	)
+
+	BeforeEach(func() {
+		var err error
+		location, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		tarballPath = filepath.Join(location, "stemcell.tgz")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(location)).To(Succeed())
+	})
+
+	It("writes the manifests next to the tarball and parses stemcell.MF", func() {
+		writeTarball(tarballPath,
+			tarEntry{Name: "./stemcell.MF", Contents: []byte(stemcellMF)},
+			tarEntry{Name: "image", Contents: []byte("some image")},
+			tarEntry{Name: "stemcell_dpkg_l.txt", Contents: []byte(dpkgList)},
+			tarEntry{Name: "packages.txt", Contents: []byte("bosh-agent 2.500.0\n")},
+		)
+
+		manifest, err := tarball.ExtractManifest(tarballPath, location)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manifest.Name).To(Equal("bosh-aws-xen-hvm-ubuntu-jammy-go_agent"))
+		Expect(manifest.Version).To(Equal("1.100"))
+		Expect(manifest.APIVersion).To(Equal(3))
+		Expect(manifest.OperatingSystem).To(Equal("ubuntu-jammy"))
+		Expect(manifest.StemcellFormats).To(Equal([]string{"aws-light"}))
+		Expect(manifest.CloudProperties).To(HaveKeyWithValue("disk", 5120))
+		Expect(manifest.AgentVersion).To(Equal("2.500.0"))
+
+		for name, contents := range map[string]string{
+			"stemcell.MF":         stemcellMF,
+			"stemcell_dpkg_l.txt": dpkgList,
+			"packages.txt":        "bosh-agent 2.500.0\n",
+		} {
+			written, err := os.ReadFile(filepath.Join(location, name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(written)).To(Equal(contents))
+		}
+
+		_, err = os.Stat(filepath.Join(location, "image"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("finds the agent version in the dpkg listing", func() {
+		writeTarball(tarballPath,
+			tarEntry{Name: "stemcell.MF", Contents: []byte(stemcellMF)},
+			tarEntry{Name: "stemcell_dpkg_l.txt", Contents: []byte(dpkgList + "ii  bosh-agent     2.600.0      amd64        bosh agent\n")},
+		)
+
+		manifest, err := tarball.ExtractManifest(tarballPath, location)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.AgentVersion).To(Equal("2.600.0"))
+	})
+
+	It("leaves the agent version empty when it is not listed", func() {
+		writeTarball(tarballPath,
+			tarEntry{Name: "stemcell.MF", Contents: []byte(stemcellMF)},
+			tarEntry{Name: "stemcell_dpkg_l.txt", Contents: []byte(dpkgList)},
+		)
+
+		manifest, err := tarball.ExtractManifest(tarballPath, location)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.AgentVersion).To(BeEmpty())
+	})
+
+	Context("when the tarball has no stemcell.MF", func() {
+		It("returns an error", func() {
+			writeTarball(tarballPath, tarEntry{Name: "image", Contents: []byte("some image")})
+
+			_, err := tarball.ExtractManifest(tarballPath, location)
+			Expect(err).To(MatchError("stemcell tarball does not contain a stemcell.MF"))
+		})
+	})
+
+	Context("when the tarball is not gzipped", func() {
+		It("returns an error", func() {
+			Expect(os.WriteFile(tarballPath, []byte("not a tarball"), 0644)).To(Succeed())
+
+			_, err := tarball.ExtractManifest(tarballPath, location)
+			Expect(err).To(MatchError(ContainSubstring("failed to read stemcell tarball")))
+		})
+	})
+})
+
+var _ = Describe("Manifest", func() {
+	Describe("Validate", func() {
+		var manifest tarball.Manifest
+
+		BeforeEach(func() {
+			manifest = tarball.Manifest{Name: "some-stemcell", Version: "1.100"}
+		})
+
+		It("succeeds when the name and version match", func() {
+			Expect(manifest.Validate(boshio.Stemcell{Name: "some-stemcell", Version: "1.100"})).To(Succeed())
+		})
+
+		It("returns an error when the name does not match", func() {
+			err := manifest.Validate(boshio.Stemcell{Name: "other-stemcell", Version: "1.100"})
+			Expect(err).To(MatchError("stemcell.MF name 'some-stemcell' does not match the requested stemcell 'other-stemcell'"))
+		})
+
+		It("returns an error when the version does not match", func() {
+			err := manifest.Validate(boshio.Stemcell{Name: "some-stemcell", Version: "1.101"})
+			Expect(err).To(MatchError("stemcell.MF version '1.100' does not match the requested version '1.101'"))
+		})
+	})
+})
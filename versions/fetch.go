@@ -0,0 +1,61 @@
+package versions
+
+import (
+	"fmt"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+)
+
+// FetchStemcells fetches the stemcells the versions of the names are chosen
+// from, along with the invalid entries that were skipped. The latest
+// stemcells of a single name are requested first, as they are a fraction of
+// the full history, which is only fetched when they fall short. Only the
+// versions published for every name with every required flavor are returned.
+func FetchStemcells(client *boshio.Client, names []string, requireFlavors []string, options FilterOptions) (boshio.Stemcells, boshio.InvalidStemcells, error) {
+	// the latest versions only suffice for a single name, as the versions
+	// common to several names or with every required flavor may be older
+	latestOnly := len(names) == 1 && len(requireFlavors) == 0 && !historyRequired(options)
+
+	var stemcellsByName []boshio.Stemcells
+	var invalid boshio.InvalidStemcells
+	for _, name := range names {
+		var stemcells boshio.Stemcells
+		var invalidForName boshio.InvalidStemcells
+		var err error
+		if latestOnly {
+			stemcells, invalidForName, err = client.GetLatestStemcells(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed getting stemcell: %s", err)
+			}
+		}
+
+		if !stemcells.HasLatestFlavor() || RequiresHistory(stemcells.FilterByType(), options) {
+			stemcells, invalidForName, err = client.GetStemcells(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed getting stemcell: %s", err)
+			}
+		}
+
+		// bosh.io returns no versions rather than an error for a misspelled
+		// name, which would otherwise go unnoticed
+		if len(stemcells) == 0 {
+			err = client.ValidateName(name)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		stemcells = stemcells.FilterByType()
+		stemcells, err = stemcells.FilterByFlavors(requireFlavors)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed filtering flavors: %s", err)
+		}
+
+		stemcellsByName = append(stemcellsByName, stemcells)
+		invalid = append(invalid, invalidForName...)
+	}
+
+	// only versions available for every name move forward, so that all
+	// stemcells are kept in lockstep
+	return stemcellsByName[0].CommonVersions(stemcellsByName[1:]...), invalid, nil
+}
@@ -0,0 +1,157 @@
+package versions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/concourse/bosh-io-stemcell-resource/boshio"
+	"github.com/concourse/bosh-io-stemcell-resource/versions"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	latestStemcells = `[
+		{"name": "%[1]s", "version": "1.2", "light": {"url": "https://example.com/light-1.2", "sha1": "light-1.2"}, "regular": {"url": "https://example.com/1.2", "sha1": "1.2"}}
+	]`
+	allStemcells = `[
+		{"name": "%[1]s", "version": "1.2", "light": {"url": "https://example.com/light-1.2", "sha1": "light-1.2"}, "regular": {"url": "https://example.com/1.2", "sha1": "1.2"}},
+		{"name": "%[1]s", "version": "1.1", "light": {"url": "https://example.com/light-1.1", "sha1": "light-1.1"}, "regular": {"url": "https://example.com/1.1", "sha1": "1.1"}}
+	]`
+	// the light flavor of the latest version is not published yet
+	pendingLatestStemcells = `[
+		{"name": "%[1]s", "version": "1.3", "regular": {"url": "https://example.com/1.3", "sha1": "1.3"}}
+	]`
+	pendingAllStemcells = `[
+		{"name": "%[1]s", "version": "1.3", "regular": {"url": "https://example.com/1.3", "sha1": "1.3"}},
+		{"name": "%[1]s", "version": "1.2", "light": {"url": "https://example.com/light-1.2", "sha1": "light-1.2"}, "regular": {"url": "https://example.com/1.2", "sha1": "1.2"}}
+	]`
+)
+
+var _ = Describe("FetchStemcells", func() {
+	var (
+		server      *httptest.Server
+		client      *boshio.Client
+		mutex       sync.Mutex
+		requestURIs []string
+	)
+
+	BeforeEach(func() {
+		requestURIs = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mutex.Lock()
+			requestURIs = append(requestURIs, req.URL.RequestURI())
+			mutex.Unlock()
+
+			if req.URL.Path == "/api/v1/stemcells" {
+				w.Write([]byte(`[{"name": "some-stemcell"}, {"name": "pending-stemcell"}]`))
+				return
+			}
+
+			name := strings.TrimPrefix(req.URL.Path, "/api/v1/stemcells/")
+			all := req.URL.Query().Get("all") == "1"
+			switch {
+			case name == "missing-stemcell":
+				w.Write([]byte(`[]`))
+			case name == "pending-stemcell" && all:
+				fmt.Fprintf(w, pendingAllStemcells, name)
+			case name == "pending-stemcell":
+				fmt.Fprintf(w, pendingLatestStemcells, name)
+			case all:
+				fmt.Fprintf(w, allStemcells, name)
+			default:
+				fmt.Fprintf(w, latestStemcells, name)
+			}
+		}))
+		client = boshio.NewClient(boshio.NewHTTPClient(server.URL, time.Millisecond), nil, nil, false)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fetch := func(names []string, requireFlavors []string, options versions.FilterOptions) boshio.Stemcells {
+		stemcells, _, err := versions.FetchStemcells(client, names, requireFlavors, options)
+		Expect(err).NotTo(HaveOccurred())
+		return stemcells
+	}
+
+	stemcellVersions := func(stemcells boshio.Stemcells) []string {
+		var list []string
+		for _, s := range stemcells {
+			list = append(list, s.Version)
+		}
+		return list
+	}
+
+	It("requests only the latest stemcells on the first check", func() {
+		stemcells := fetch([]string{"some-stemcell"}, nil, versions.FilterOptions{})
+
+		Expect(stemcellVersions(stemcells)).To(Equal([]string{"1.2"}))
+		Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells/some-stemcell"}))
+	})
+
+	It("requests the history when the initial history goes beyond the latest stemcells", func() {
+		stemcells := fetch([]string{"some-stemcell"}, nil, versions.FilterOptions{InitialHistory: 2})
+
+		Expect(stemcellVersions(stemcells)).To(Equal([]string{"1.2", "1.1"}))
+		Expect(requestURIs).To(Equal([]string{
+			"/api/v1/stemcells/some-stemcell",
+			"/api/v1/stemcells/some-stemcell?all=1",
+		}))
+	})
+
+	It("requests only the history once there is a previous version", func() {
+		stemcells := fetch([]string{"some-stemcell"}, nil, versions.FilterOptions{InitialVersion: "1.1"})
+
+		Expect(stemcellVersions(stemcells)).To(Equal([]string{"1.2", "1.1"}))
+		Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells/some-stemcell?all=1"}))
+	})
+
+	It("requests only the history for a version family", func() {
+		fetch([]string{"some-stemcell"}, nil, versions.FilterOptions{VersionFamily: "1.1"})
+
+		Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells/some-stemcell?all=1"}))
+	})
+
+	It("requests the history when the latest version is still waiting for its light flavor", func() {
+		stemcells := fetch([]string{"pending-stemcell"}, nil, versions.FilterOptions{})
+
+		Expect(stemcellVersions(stemcells)).To(Equal([]string{"1.2"}))
+		Expect(requestURIs).To(Equal([]string{
+			"/api/v1/stemcells/pending-stemcell",
+			"/api/v1/stemcells/pending-stemcell?all=1",
+		}))
+	})
+
+	It("requests only the history of each of several names", func() {
+		fetch([]string{"some-stemcell", "other-stemcell"}, nil, versions.FilterOptions{})
+
+		Expect(requestURIs).To(Equal([]string{
+			"/api/v1/stemcells/some-stemcell?all=1",
+			"/api/v1/stemcells/other-stemcell?all=1",
+		}))
+	})
+
+	It("requests only the history when flavors are required", func() {
+		fetch([]string{"some-stemcell"}, []string{"light", "regular"}, versions.FilterOptions{})
+
+		Expect(requestURIs).To(Equal([]string{"/api/v1/stemcells/some-stemcell?all=1"}))
+	})
+
+	It("lists the published names when a name has no stemcells", func() {
+		_, _, err := versions.FetchStemcells(client, []string{"missing-stemcell"}, nil, versions.FilterOptions{})
+		Expect(err).To(BeAssignableToTypeOf(boshio.UnknownNameError{}))
+
+		Expect(requestURIs).To(Equal([]string{
+			"/api/v1/stemcells/missing-stemcell",
+			"/api/v1/stemcells/missing-stemcell?all=1",
+			"/api/v1/stemcells",
+		}))
+	})
+})
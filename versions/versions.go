@@ -11,27 +11,79 @@ import (
 
 type StemcellVersions []map[string]string
 
+// ChecksumField adds the sha256 of the stemcell to each version, or the sha1
+// when no sha256 is published.
+const ChecksumField = "checksum"
+
+// versionFields are the extra fields which can be added to each version. They
+// are derived from the published stemcell only, so that a version keeps the
+// same identity across checks.
+var versionFields = map[string]func(boshio.Stemcell) string{
+	"flavor": func(s boshio.Stemcell) string { return s.Flavor() },
+	"md5":    func(s boshio.Stemcell) string { return s.Details().MD5 },
+	"sha1":   func(s boshio.Stemcell) string { return s.Details().SHA1 },
+	"sha256": func(s boshio.Stemcell) string { return s.Details().SHA256 },
+}
+
+// FilterOptions configures which of the published versions a Filter emits.
+type FilterOptions struct {
+	// InitialVersion is the version of the previous check, versions older
+	// than it are not emitted.
+	InitialVersion string
+	// InitialFields are the extra fields the initial version was emitted
+	// with, so that it keeps its identity when bosh.io publishes another
+	// flavor of it.
+	InitialFields map[string]string
+	VersionFamily string
+	// InitialHistory is the number of versions to emit when there is no
+	// initial version.
+	InitialHistory int
+	// Fields are the extra fields to add to each version, see FieldNames.
+	Fields []string
+}
+
 type Filter struct {
-	initialVersion string
-	stemcells      []boshio.Stemcell
-	versionFamily  string
+	stemcells []boshio.Stemcell
+	options   FilterOptions
 }
 
-func NewFilter(initialVersion string, stemcells []boshio.Stemcell, versionFamily string) Filter {
+func NewFilter(stemcells []boshio.Stemcell, options FilterOptions) Filter {
 	return Filter{
-		initialVersion: initialVersion,
-		stemcells:      stemcells,
-		versionFamily:  versionFamily,
+		stemcells: stemcells,
+		options:   options,
 	}
 }
 
+// RequiresHistory reports whether the latest published stemcells fall short
+// of the versions to emit, so that the full version history has to be
+// fetched. Only the first check of the latest versions can do without it, as
+// bosh.io does not document which versions it returns as the latest, so the
+// versions published since a previous version may be missing from them.
+func RequiresHistory(latest []boshio.Stemcell, options FilterOptions) bool {
+	return historyRequired(options) || options.InitialHistory > len(latest)
+}
+
+// historyRequired reports whether the full version history is needed
+// whatever the latest stemcells are.
+func historyRequired(options FilterOptions) bool {
+	if options.InitialVersion != "" {
+		return true
+	}
+
+	return options.VersionFamily != "" && options.VersionFamily != "latest"
+}
+
 func (f Filter) Versions() (StemcellVersions, error) {
 	if len(f.stemcells) == 0 {
 		return StemcellVersions{}, nil
 	}
 
-	stemcellVersions := f.mapStemcellsToVersions(f.stemcells)
-	if len(f.versionFamily) > 0 && f.versionFamily != "latest" {
+	stemcellVersions, err := f.mapStemcellsToVersions(f.stemcells)
+	if err != nil {
+		return StemcellVersions{}, err
+	}
+
+	if len(f.options.VersionFamily) > 0 && f.options.VersionFamily != "latest" {
 		var err error
 		stemcellVersions, err = f.filterStemcellsByVersionFamily(stemcellVersions)
 		if err != nil {
@@ -45,25 +97,145 @@ func (f Filter) Versions() (StemcellVersions, error) {
 
 	sort.Sort(stemcellVersions)
 
-	if f.initialVersion == "" {
-		return stemcellVersions[len(stemcellVersions)-1:], nil
+	if f.options.InitialVersion == "" {
+		return f.selectInitialHistory(stemcellVersions), nil
 	}
 
 	return f.selectVersionsGreaterThanInitial(stemcellVersions)
 }
 
-func (f Filter) mapStemcellsToVersions(stemcells []boshio.Stemcell) StemcellVersions {
+// Selects reports whether the version would be emitted if it were published
+// alongside the stemcells of the filter.
+func (f Filter) Selects(version string) (bool, error) {
+	if _, err := semver.ParseTolerant(version); err != nil {
+		return false, nil
+	}
+
+	stemcells := []boshio.Stemcell{{Version: version, Regular: &boshio.Metadata{}}}
+	for _, s := range f.stemcells {
+		if s.Version != version {
+			stemcells = append(stemcells, s)
+		}
+	}
+
+	selected, err := NewFilter(stemcells, FilterOptions{
+		InitialVersion: f.options.InitialVersion,
+		VersionFamily:  f.options.VersionFamily,
+		InitialHistory: f.options.InitialHistory,
+	}).Versions()
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range selected {
+		if v["version"] == version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f Filter) mapStemcellsToVersions(stemcells []boshio.Stemcell) (StemcellVersions, error) {
 	versions := StemcellVersions{}
 	for _, s := range stemcells {
-		versions = append(versions, map[string]string{"version": s.Version})
+		version, err := f.versionOf(s)
+		if err != nil {
+			return StemcellVersions{}, err
+		}
+
+		if s.Version == f.options.InitialVersion && len(f.options.InitialFields) > 0 {
+			version, err = f.anchorInitialVersion(s, version)
+			if err != nil {
+				return StemcellVersions{}, err
+			}
+		}
+		versions = append(versions, version)
 	}
-	return versions
+	return versions, nil
+}
+
+// anchorInitialVersion keeps the fields the initial version was emitted with
+// as long as the flavor they describe is still published unchanged, as the
+// flavor preferred for a version changes once its light flavor is published.
+func (f Filter) anchorInitialVersion(s boshio.Stemcell, version map[string]string) (map[string]string, error) {
+	for _, flavor := range []string{boshio.FlavorLight, boshio.FlavorRegular} {
+		flavored, err := s.WithFlavor(flavor)
+		if err != nil {
+			continue
+		}
+
+		candidate, err := f.versionOf(flavored)
+		if err != nil {
+			return nil, err
+		}
+
+		if matchesFields(candidate, f.options.InitialFields) {
+			return candidate, nil
+		}
+	}
+
+	return version, nil
+}
+
+// matchesFields reports whether the version has exactly the given extra
+// fields, its version number aside.
+func matchesFields(version map[string]string, fields map[string]string) bool {
+	for name, value := range version {
+		if name != "version" && fields[name] != value {
+			return false
+		}
+	}
+	for name, value := range fields {
+		if name != "version" && version[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Filter) versionOf(s boshio.Stemcell) (map[string]string, error) {
+	version := map[string]string{"version": s.Version}
+	for _, field := range f.options.Fields {
+		if field == ChecksumField {
+			// include the checksum so that a republished version with
+			// different bits is detected as a new version
+			if s.Details().SHA256 != "" {
+				version["sha256"] = s.Details().SHA256
+			} else {
+				version["sha1"] = s.Details().SHA1
+			}
+			continue
+		}
+
+		value, ok := versionFields[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown version field '%s': must be one of %s", field, strings.Join(FieldNames(), ", "))
+		}
+
+		// fields which are not published for the stemcell are left out
+		// rather than set to an empty string
+		if v := value(s); v != "" {
+			version[field] = v
+		}
+	}
+	return version, nil
+}
+
+// FieldNames returns the names of the fields which can be added to each
+// version.
+func FieldNames() []string {
+	names := []string{ChecksumField}
+	for name := range versionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (f Filter) filterStemcellsByVersionFamily(stemcells StemcellVersions) (StemcellVersions, error) {
-	familyVersion := f.versionFamily
-	if strings.Contains(f.versionFamily, ".latest") {
-		familyVersion = f.versionFamily[0 : len(f.versionFamily)-len(".latest")]
+	familyVersion := f.options.VersionFamily
+	if strings.Contains(f.options.VersionFamily, ".latest") {
+		familyVersion = f.options.VersionFamily[0 : len(f.options.VersionFamily)-len(".latest")]
 	}
 
 	parsedVersion, err := semver.ParseTolerant(familyVersion)
@@ -100,8 +272,22 @@ func (f Filter) filterStemcellsByVersionFamily(stemcells StemcellVersions) (Stem
 	return filteredStemcells, nil
 }
 
+// selectInitialHistory returns the most recent versions to emit when there is
+// no previous version, so that a new resource has some history to pin to.
+func (f Filter) selectInitialHistory(stemcells StemcellVersions) StemcellVersions {
+	count := f.options.InitialHistory
+	if count < 1 {
+		count = 1
+	}
+	if count > len(stemcells) {
+		count = len(stemcells)
+	}
+
+	return stemcells[len(stemcells)-count:]
+}
+
 func (f Filter) selectVersionsGreaterThanInitial(stemcells StemcellVersions) (StemcellVersions, error) {
-	parsedInitialVersion, err := semver.ParseTolerant(f.initialVersion)
+	parsedInitialVersion, err := semver.ParseTolerant(f.options.InitialVersion)
 	if err != nil {
 		return StemcellVersions{}, nil
 	}
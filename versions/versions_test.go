@@ -20,7 +20,7 @@ var _ = Describe("Versions", func() {
 				{Version: "3232"},
 			}
 
-			filter = versions.NewFilter("", stemcells, "")
+			filter = versions.NewFilter(stemcells, versions.FilterOptions{})
 		})
 
 		It("returns the latest version", func() {
@@ -33,6 +33,183 @@ var _ = Describe("Versions", func() {
 		})
 	})
 
+	Context("when provided with no starting version and an initial history", func() {
+		var stemcells []boshio.Stemcell
+
+		BeforeEach(func() {
+			stemcells = []boshio.Stemcell{
+				{Version: "3233.1"},
+				{Version: "3232.9"},
+				{Version: "3232.8"},
+				{Version: "3232.1"},
+				{Version: "3232"},
+			}
+		})
+
+		It("returns the N most recent versions", func() {
+			list, err := versions.NewFilter(stemcells, versions.FilterOptions{InitialHistory: 3}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(list).To(Equal(versions.StemcellVersions{
+				{"version": "3232.8"},
+				{"version": "3232.9"},
+				{"version": "3233.1"},
+			}))
+		})
+
+		It("only returns versions within the version family", func() {
+			list, err := versions.NewFilter(stemcells, versions.FilterOptions{VersionFamily: "3232.latest", InitialHistory: 2}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(list).To(Equal(versions.StemcellVersions{
+				{"version": "3232.8"},
+				{"version": "3232.9"},
+			}))
+		})
+
+		Context("when the history is larger than the available versions", func() {
+			It("returns every version", func() {
+				list, err := versions.NewFilter(stemcells, versions.FilterOptions{VersionFamily: "3232.latest", InitialHistory: 10}).Versions()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(list).To(Equal(versions.StemcellVersions{
+					{"version": "3232"},
+					{"version": "3232.1"},
+					{"version": "3232.8"},
+					{"version": "3232.9"},
+				}))
+			})
+		})
+
+		Context("when a starting version is also provided", func() {
+			It("ignores the initial history", func() {
+				list, err := versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.9", InitialHistory: 3}).Versions()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(list).To(Equal(versions.StemcellVersions{
+					{"version": "3232.9"},
+					{"version": "3233.1"},
+				}))
+			})
+		})
+	})
+
+	Context("when tracking checksums", func() {
+		var stemcells []boshio.Stemcell
+
+		BeforeEach(func() {
+			stemcells = []boshio.Stemcell{
+				{Version: "3232.9", Light: &boshio.Metadata{SHA1: "light-sha1-9", SHA256: "light-sha256-9"}},
+				{Version: "3232.8", Regular: &boshio.Metadata{SHA1: "regular-sha1-8"}},
+				{Version: "3232.1", Regular: &boshio.Metadata{SHA1: "regular-sha1-1", SHA256: "regular-sha256-1"}},
+			}
+		})
+
+		It("includes the sha256 in each version", func() {
+			list, err := versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.9", Fields: []string{versions.ChecksumField}}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(list).To(Equal(versions.StemcellVersions{
+				{"version": "3232.9", "sha256": "light-sha256-9"},
+			}))
+		})
+
+		It("falls back to the sha1 when no sha256 is published", func() {
+			list, err := versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.1", Fields: []string{versions.ChecksumField}}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(list).To(Equal(versions.StemcellVersions{
+				{"version": "3232.1", "sha256": "regular-sha256-1"},
+				{"version": "3232.8", "sha1": "regular-sha1-8"},
+				{"version": "3232.9", "sha256": "light-sha256-9"},
+			}))
+		})
+	})
+
+	Context("when adding version fields", func() {
+		var stemcells []boshio.Stemcell
+
+		BeforeEach(func() {
+			stemcells = []boshio.Stemcell{
+				{Version: "3232.9", Light: &boshio.Metadata{MD5: "light-md5-9", SHA1: "light-sha1-9", SHA256: "light-sha256-9"}},
+				{Version: "3232.8", Regular: &boshio.Metadata{SHA1: "regular-sha1-8"}},
+			}
+		})
+
+		It("includes each field in each version", func() {
+			list, err := versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.8", Fields: []string{"flavor", "sha1", "md5"}}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(list).To(Equal(versions.StemcellVersions{
+				{"version": "3232.8", "flavor": "regular", "sha1": "regular-sha1-8"},
+				{"version": "3232.9", "flavor": "light", "sha1": "light-sha1-9", "md5": "light-md5-9"},
+			}))
+		})
+
+		It("keeps the identity of the previous version when another flavor of it is published", func() {
+			fields := []string{"flavor", "sha256"}
+			firstCheck := []boshio.Stemcell{
+				{Version: "3232.8", Regular: &boshio.Metadata{MD5: "regular-md5-8", SHA1: "regular-sha1-8", SHA256: "regular-sha256-8"}},
+			}
+
+			first, err := versions.NewFilter(firstCheck, versions.FilterOptions{Fields: fields}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).To(Equal(versions.StemcellVersions{
+				{"version": "3232.8", "flavor": "regular", "sha256": "regular-sha256-8"},
+			}))
+
+			// the light flavor is published hours later, and the md5 and sha1
+			// of the regular flavor, which are not part of the identity, change
+			secondCheck := []boshio.Stemcell{
+				{
+					Version: "3232.8",
+					Light:   &boshio.Metadata{SHA1: "light-sha1-8", SHA256: "light-sha256-8"},
+					Regular: &boshio.Metadata{MD5: "new-regular-md5-8", SHA1: "new-regular-sha1-8", SHA256: "regular-sha256-8"},
+				},
+				{
+					Version: "3232.9",
+					Light:   &boshio.Metadata{SHA1: "light-sha1-9", SHA256: "light-sha256-9"},
+					Regular: &boshio.Metadata{SHA1: "regular-sha1-9", SHA256: "regular-sha256-9"},
+				},
+			}
+
+			second, err := versions.NewFilter(secondCheck, versions.FilterOptions{
+				InitialVersion: first[0]["version"],
+				InitialFields:  first[0],
+				Fields:         fields,
+			}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(second).To(Equal(versions.StemcellVersions{
+				first[0],
+				{"version": "3232.9", "flavor": "light", "sha256": "light-sha256-9"},
+			}))
+		})
+
+		It("gives the previous version a new identity when it is republished", func() {
+			fields := []string{"flavor", "sha256"}
+			republished := []boshio.Stemcell{
+				{Version: "3232.8", Regular: &boshio.Metadata{SHA1: "new-regular-sha1-8", SHA256: "new-regular-sha256-8"}},
+			}
+
+			list, err := versions.NewFilter(republished, versions.FilterOptions{
+				InitialVersion: "3232.8",
+				InitialFields:  map[string]string{"flavor": "regular", "sha256": "regular-sha256-8"},
+				Fields:         fields,
+			}).Versions()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(list).To(Equal(versions.StemcellVersions{
+				{"version": "3232.8", "flavor": "regular", "sha256": "new-regular-sha256-8"},
+			}))
+		})
+
+		It("errors on an unknown field", func() {
+			_, err := versions.NewFilter(stemcells, versions.FilterOptions{Fields: []string{"url"}}).Versions()
+			Expect(err).To(MatchError("unknown version field 'url': must be one of checksum, flavor, md5, sha1, sha256"))
+		})
+	})
+
 	Context("when the versions are out of order", func() {
 		var filter versions.Filter
 
@@ -46,7 +223,7 @@ var _ = Describe("Versions", func() {
 				{Version: "3333"},
 			}
 
-			filter = versions.NewFilter("3232.1", stemcells, "")
+			filter = versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.1"})
 		})
 
 		It("orders them perfectly", func() {
@@ -78,7 +255,7 @@ var _ = Describe("Versions", func() {
 				{Version: "3232"},
 			}
 
-			filter = versions.NewFilter("3232.4", stemcells, "")
+			filter = versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.4"})
 		})
 
 		It("returns all the versions newer than the provided version", func() {
@@ -110,7 +287,7 @@ var _ = Describe("Versions", func() {
 				{Version: "3232"},
 			}
 
-			filter = versions.NewFilter("", stemcells, "3232.7")
+			filter = versions.NewFilter(stemcells, versions.FilterOptions{VersionFamily: "3232.7"})
 		})
 
 		It("returns the latest version within the family", func() {
@@ -137,7 +314,7 @@ var _ = Describe("Versions", func() {
 					{Version: "3232"},
 				}
 
-				filter = versions.NewFilter("", stemcells, "9999")
+				filter = versions.NewFilter(stemcells, versions.FilterOptions{VersionFamily: "9999"})
 			})
 
 			It("returns an empty version list", func() {
@@ -160,7 +337,7 @@ var _ = Describe("Versions", func() {
 					{Version: "3232.7"},
 				}
 
-				filter = versions.NewFilter("3233.2", stemcells, "3233")
+				filter = versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3233.2", VersionFamily: "3233"})
 			})
 
 			It("returns all the versions within the family >= the initial version", func() {
@@ -181,7 +358,7 @@ var _ = Describe("Versions", func() {
 		BeforeEach(func() {
 			stemcells := []boshio.Stemcell{}
 
-			filter = versions.NewFilter("", stemcells, "")
+			filter = versions.NewFilter(stemcells, versions.FilterOptions{})
 		})
 
 		It("returns an empty list", func() {
@@ -198,7 +375,7 @@ var _ = Describe("Versions", func() {
 		BeforeEach(func() {
 			stemcells := []boshio.Stemcell{}
 
-			filter = versions.NewFilter("3232.4", stemcells, "")
+			filter = versions.NewFilter(stemcells, versions.FilterOptions{InitialVersion: "3232.4"})
 		})
 
 		It("returns an empty list", func() {
@@ -209,3 +386,51 @@ var _ = Describe("Versions", func() {
 		})
 	})
 })
+
+var _ = Describe("Selects", func() {
+	var stemcells []boshio.Stemcell
+
+	BeforeEach(func() {
+		stemcells = []boshio.Stemcell{
+			{Version: "3233.1", Regular: &boshio.Metadata{}},
+			{Version: "3232.9", Regular: &boshio.Metadata{}},
+			{Version: "3232.4", Regular: &boshio.Metadata{}},
+		}
+	})
+
+	DescribeTable("reports whether a version would be emitted",
+		func(options versions.FilterOptions, version string, expected bool) {
+			selected, err := versions.NewFilter(stemcells, options).Versions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selected).NotTo(BeEmpty())
+
+			Expect(versions.NewFilter(stemcells, options).Selects(version)).To(Equal(expected))
+		},
+		Entry("newer than the previous version", versions.FilterOptions{InitialVersion: "3232.9"}, "3232.10", true),
+		Entry("older than the previous version", versions.FilterOptions{InitialVersion: "3232.9"}, "3232.5", false),
+		Entry("the latest on the first check", versions.FilterOptions{}, "3234", true),
+		Entry("older than the latest on the first check", versions.FilterOptions{}, "3232.9", false),
+		Entry("within the initial history", versions.FilterOptions{InitialHistory: 3}, "3232.5", true),
+		Entry("outside of the version family", versions.FilterOptions{VersionFamily: "3232.latest"}, "3234", false),
+		Entry("without a semantic version", versions.FilterOptions{}, "", false),
+	)
+})
+
+var _ = Describe("RequiresHistory", func() {
+	latest := []boshio.Stemcell{
+		{Version: "3262.5"},
+		{Version: "3262.4"},
+	}
+
+	DescribeTable("reports whether versions older than the latest published are needed",
+		func(options versions.FilterOptions, expected bool) {
+			Expect(versions.RequiresHistory(latest, options)).To(Equal(expected))
+		},
+		Entry("on the first check", versions.FilterOptions{}, false),
+		Entry("on the first check of the latest family", versions.FilterOptions{VersionFamily: "latest"}, false),
+		Entry("with an initial history within the latest", versions.FilterOptions{InitialHistory: 2}, false),
+		Entry("with an initial history beyond the latest", versions.FilterOptions{InitialHistory: 3}, true),
+		Entry("with a previous version", versions.FilterOptions{InitialVersion: "3262.5"}, true),
+		Entry("with a version family", versions.FilterOptions{VersionFamily: "3262.latest"}, true),
+	)
+})